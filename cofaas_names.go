@@ -11,6 +11,12 @@ func (n CofaasName) Ident(i string) CofaasName {
 	return n + CofaasName(i)
 }
 
+// ProtoName returns the name of the module containing the code
+// generated for the protocol with go_package importPath
+func ProtoName(importPath string) CofaasName {
+	return ProtoNameBase.Ident(importPath)
+}
+
 func (n CofaasName) String() string {
 	return string(n)
 }
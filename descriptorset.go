@@ -0,0 +1,116 @@
+package cofaas
+
+import (
+	"os"
+
+	"github.com/go-errors/errors"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// isDescriptorSet reports whether the include path names a
+// FileDescriptorSet rather than a directory
+func isDescriptorSet(include string) bool {
+	info, err := os.Stat(include)
+	return err == nil && !info.IsDir()
+}
+
+// readDescriptorSet reads the FileDescriptorSet stored in file. Buf
+// images are accepted as well since they are wire compatible.
+func readDescriptorSet(file string) ([]*descriptorpb.FileDescriptorProto, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(data, &set); err != nil {
+		return nil, errors.Errorf("failed to read descriptor set %s: %v", file, err)
+	}
+	return set.File, nil
+}
+
+// descriptorSetFiles indexes the files of the given descriptor sets by
+// name. The first occurrence of a file takes precedence.
+func descriptorSetFiles(sets []string) (map[string]*descriptorpb.FileDescriptorProto, error) {
+	res := make(map[string]*descriptorpb.FileDescriptorProto)
+	for _, s := range sets {
+		files, err := readDescriptorSet(s)
+		if err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+		for _, f := range files {
+			if _, ok := res[f.GetName()]; !ok {
+				res[f.GetName()] = f
+			}
+		}
+	}
+	return res, nil
+}
+
+// closeDescriptors returns the files named in names and the files they
+// import such that every file precedes the files importing it
+func closeDescriptors(files map[string]*descriptorpb.FileDescriptorProto, names []string) ([]*descriptorpb.FileDescriptorProto, error) {
+	var res []*descriptorpb.FileDescriptorProto
+	visited := make(map[string]bool)
+	var visit func(name string) error
+	visit = func(name string) error {
+		if visited[name] {
+			return nil
+		}
+		visited[name] = true
+		f, ok := files[name]
+		if !ok {
+			return errors.Errorf("protocol %s is not defined in the descriptor sets", name)
+		}
+		for _, dep := range f.GetDependency() {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		res = append(res, f)
+		return nil
+	}
+	for _, n := range names {
+		if err := visit(n); err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+	}
+	return res, nil
+}
+
+// mergeDescriptors appends the files of b which are not in a to a
+func mergeDescriptors(a, b []*descriptorpb.FileDescriptorProto) []*descriptorpb.FileDescriptorProto {
+	seen := make(map[string]bool)
+	for _, f := range a {
+		seen[f.GetName()] = true
+	}
+	for _, f := range b {
+		if !seen[f.GetName()] {
+			a = append(a, f)
+		}
+	}
+	return a
+}
+
+// DescriptorSetFile returns the name of the protocol file of the
+// descriptor set stored in set which is named name. If name is empty,
+// the last file of the set is returned which is the file given last to
+// the compiler producing it.
+func DescriptorSetFile(set string, name string) (string, error) {
+	files, err := readDescriptorSet(set)
+	if err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+	if len(files) == 0 {
+		return "", errors.Errorf("descriptor set %s is empty", set)
+	}
+	if name == "" {
+		return files[len(files)-1].GetName(), nil
+	}
+	for _, f := range files {
+		if f.GetName() == name {
+			return name, nil
+		}
+	}
+	return "", errors.Errorf("descriptor set %s does not define %s", set, name)
+}
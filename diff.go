@@ -0,0 +1,150 @@
+package cofaas
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// diffContext is the number of unchanged lines surrounding the changes
+// of a hunk
+const diffContext = 3
+
+type diffLine struct {
+	op   diffmatchpatch.Operation
+	text string
+}
+
+// diffLines returns the lines of a and b annotated with whether they
+// are only in a, only in b or in both
+func diffLines(a string, b string) []diffLine {
+	// The lines are diffed as runes each representing a distinct
+	// line
+	var lines []string
+	index := make(map[string]rune)
+	toRunes := func(text string) []rune {
+		var res []rune
+		for _, l := range strings.SplitAfter(text, "\n") {
+			if l == "" {
+				continue
+			}
+			r, ok := index[l]
+			if !ok {
+				r = rune(len(lines) + 1)
+				// Skip the surrogate halves which are not valid
+				// runes
+				if r >= 0xd800 {
+					r += 0x800
+				}
+				index[l] = r
+				lines = append(lines, l)
+			}
+			res = append(res, r)
+		}
+		return res
+	}
+	runesA, runesB := toRunes(a), toRunes(b)
+	line := func(r rune) string {
+		if r >= 0xe000 {
+			r -= 0x800
+		}
+		return strings.TrimSuffix(lines[r-1], "\n")
+	}
+
+	var res []diffLine
+	for _, d := range diffmatchpatch.New().DiffMainRunes(runesA, runesB, false) {
+		for _, r := range d.Text {
+			res = append(res, diffLine{op: d.Type, text: line(r)})
+		}
+	}
+	return res
+}
+
+// UnifiedDiff returns a unified diff of the changes from a to b of the
+// file name. The empty string is returned if a and b are equal.
+func UnifiedDiff(name string, a string, b string) string {
+	if a == b {
+		return ""
+	}
+	lines := diffLines(a, b)
+
+	var res strings.Builder
+	fmt.Fprintf(&res, "--- a/%s\n+++ b/%s\n", name, name)
+	// Line numbers of the first line of the hunk in a and b
+	aLine, bLine := 1, 1
+	for i := 0; i < len(lines); {
+		if lines[i].op == diffmatchpatch.DiffEqual {
+			aLine++
+			bLine++
+			i++
+			continue
+		}
+
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		// Extend the hunk while the next change is close enough for
+		// the context of the hunks to overlap
+		end := i
+		for end < len(lines) {
+			if lines[end].op != diffmatchpatch.DiffEqual {
+				end++
+				continue
+			}
+			next := end
+			for next < len(lines) && lines[next].op == diffmatchpatch.DiffEqual {
+				next++
+			}
+			if next < len(lines) && next-end <= 2*diffContext {
+				end = next
+				continue
+			}
+			end += diffContext
+			if end > next {
+				end = next
+			}
+			break
+		}
+
+		aStart, bStart := aLine-(i-start), bLine-(i-start)
+		var aCount, bCount int
+		var hunk strings.Builder
+		for _, l := range lines[start:end] {
+			switch l.op {
+			case diffmatchpatch.DiffEqual:
+				aCount++
+				bCount++
+				hunk.WriteString(" ")
+			case diffmatchpatch.DiffDelete:
+				aCount++
+				hunk.WriteString("-")
+			case diffmatchpatch.DiffInsert:
+				bCount++
+				hunk.WriteString("+")
+			}
+			hunk.WriteString(l.text)
+			hunk.WriteString("\n")
+		}
+		// Empty ranges are numbered by the line preceding them
+		if aCount == 0 {
+			aStart--
+		}
+		if bCount == 0 {
+			bStart--
+		}
+		fmt.Fprintf(&res, "@@ -%d,%d +%d,%d @@\n%s", aStart, aCount, bStart, bCount, hunk.String())
+
+		for _, l := range lines[i:end] {
+			if l.op != diffmatchpatch.DiffInsert {
+				aLine++
+			}
+			if l.op != diffmatchpatch.DiffDelete {
+				bLine++
+			}
+		}
+		i = end
+	}
+	return res.String()
+}
@@ -0,0 +1,63 @@
+package cofaas
+
+import (
+	"strings"
+	"testing"
+)
+
+func numberedLines(from, to int, replace map[int]string) string {
+	var res strings.Builder
+	for i := from; i <= to; i++ {
+		if r, ok := replace[i]; ok {
+			if r != "" {
+				res.WriteString(r + "\n")
+			}
+			continue
+		}
+		res.WriteString("line" + string(rune('a'+i-1)) + "\n")
+	}
+	return res.String()
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	a := numberedLines(1, 20, nil)
+	b := numberedLines(1, 20, map[int]string{2: "changed", 6: "", 18: "end"})
+	expected := `--- a/main.go
++++ b/main.go
+@@ -1,9 +1,8 @@
+ linea
+-lineb
++changed
+ linec
+ lined
+ linee
+-linef
+ lineg
+ lineh
+ linei
+@@ -15,6 +14,6 @@
+ lineo
+ linep
+ lineq
+-liner
++end
+ lines
+ linet
+`
+	if actual := UnifiedDiff("main.go", a, b); actual != expected {
+		t.Errorf("unexpected diff\n%s", actual)
+	}
+
+	if actual := UnifiedDiff("main.go", a, a); actual != "" {
+		t.Errorf("expected no diff of equal files but got\n%s", actual)
+	}
+
+	expected = `--- a/main.go
++++ b/main.go
+@@ -0,0 +1,1 @@
++new
+`
+	if actual := UnifiedDiff("main.go", "", "new\n"); actual != expected {
+		t.Errorf("unexpected diff of new file\n%s", actual)
+	}
+}
@@ -0,0 +1,244 @@
+package cofaas
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-errors/errors"
+	cp "github.com/otiai10/copy"
+	"golang.org/x/mod/modfile"
+	pkg "golang.org/x/tools/go/packages"
+)
+
+// findUp returns the path of the file name in dir or the closest of
+// its parents containing it. The empty string is returned if there is
+// none.
+func findUp(dir string, name string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+	for {
+		p := filepath.Join(dir, name)
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		} else if !os.IsNotExist(err) {
+			return "", errors.Wrap(err, 0)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// fileImports returns the import paths of the go files
+func fileImports(files []string) ([]string, error) {
+	var res []string
+	fset := token.NewFileSet()
+	for _, file := range files {
+		f, err := parser.ParseFile(fset, file, nil, parser.ImportsOnly)
+		if err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+		for _, im := range f.Imports {
+			p, err := strconv.Unquote(im.Path.Value)
+			if err != nil {
+				return nil, errors.Wrap(err, 0)
+			}
+			res = append(res, p)
+		}
+	}
+	return res, nil
+}
+
+// copyFiles copies the regular files of the directory src to dst
+// without descending into its subdirectories
+func copyFiles(src string, dst string) error {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return errors.Wrap(err, 0)
+	}
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		if err := cp.Copy(filepath.Join(src, e.Name()), filepath.Join(dst, e.Name())); err != nil {
+			return errors.Wrap(err, 0)
+		}
+	}
+	return nil
+}
+
+// extractPackage copies the package in pkgDir, which is part of a
+// larger module, to implPath together with the packages of the module
+// it imports. The package becomes the root of a module with the go.mod
+// and go.sum of the enclosing module, which are tidied later on, and
+// the imported packages are placed at their paths relative to the
+// root of the enclosing module. The import path of the package is
+// returned.
+func extractPackage(pkgDir string, implPath string) (string, error) {
+	modFile, err := findUp(pkgDir, "go.mod")
+	if err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+	if modFile == "" {
+		return "", errors.Errorf("%s is not part of a go module", pkgDir)
+	}
+	data, err := os.ReadFile(modFile)
+	if err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+	modulePath := modfile.ModulePath(data)
+	modRoot := filepath.Dir(modFile)
+
+	absDir, err := filepath.Abs(pkgDir)
+	if err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+	rel, err := filepath.Rel(modRoot, absDir)
+	if err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+	pkgPath := path.Join(modulePath, filepath.ToSlash(rel))
+
+	if err := cp.Copy(absDir, implPath); err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+	for _, name := range []string{"go.mod", "go.sum"} {
+		src := filepath.Join(modRoot, name)
+		if _, err := os.Stat(src); os.IsNotExist(err) {
+			continue
+		}
+		if err := cp.Copy(src, filepath.Join(implPath, name)); err != nil {
+			return "", errors.Wrap(err, 0)
+		}
+	}
+
+	// The packages of the module imported by the package and its
+	// subpackages are copied until no new packages are imported
+	cfg := pkg.Config{
+		Mode: pkg.NeedName | pkg.NeedFiles,
+		Dir:  absDir}
+	pkgs, err := pkg.Load(&cfg, "./...")
+	if err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+	seen := make(map[string]bool)
+	for len(pkgs) > 0 {
+		var next []string
+		for _, p := range pkgs {
+			imports, err := fileImports(p.GoFiles)
+			if err != nil {
+				return "", errors.Wrap(err, 0)
+			}
+			for _, im := range imports {
+				if seen[im] || im == pkgPath || strings.HasPrefix(im, pkgPath+"/") {
+					continue
+				}
+				if im == modulePath {
+					return "", errors.Errorf("package %s imports the root package of module %s which cannot be extracted", p.PkgPath, modulePath)
+				}
+				if strings.HasPrefix(im, modulePath+"/") {
+					seen[im] = true
+					next = append(next, im)
+				}
+			}
+		}
+		if len(next) == 0 {
+			break
+		}
+
+		cfg.Dir = modRoot
+		if pkgs, err = pkg.Load(&cfg, next...); err != nil {
+			return "", errors.Wrap(err, 0)
+		}
+		for _, p := range pkgs {
+			if len(p.GoFiles) == 0 {
+				return "", errors.Errorf("package %s of module %s not found", p.PkgPath, modulePath)
+			}
+			dst := filepath.Join(implPath, filepath.FromSlash(strings.TrimPrefix(p.PkgPath, modulePath+"/")))
+			if _, err := os.Stat(dst); err == nil {
+				return "", errors.Errorf("package %s conflicts with the directory %s of %s", p.PkgPath, dst, pkgPath)
+			}
+			if err := copyFiles(filepath.Dir(p.GoFiles[0]), dst); err != nil {
+				return "", errors.Wrap(err, 0)
+			}
+		}
+	}
+	return pkgPath, nil
+}
+
+// useWorkspace replaces the modules of the go.work workspace
+// containing dir with their directories in the go.mod of the copied
+// module as the copy is not part of the workspace. The replacements of
+// the workspace are added as well.
+func (p *PkgRewriter) useWorkspace(dir string) error {
+	workFile, err := findUp(dir, "go.work")
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	if workFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(workFile)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	wf, err := modfile.ParseWork(workFile, data, nil)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	workDir := filepath.Dir(workFile)
+	abs := func(p string) string {
+		if filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(workDir, p)
+	}
+
+	return p.transformMod(func(f *modfile.File) error {
+		replaced := make(map[string]bool)
+		for _, r := range f.Replace {
+			replaced[r.Old.Path] = true
+		}
+		for _, u := range wf.Use {
+			useDir := abs(u.Path)
+			data, err := os.ReadFile(filepath.Join(useDir, "go.mod"))
+			if err != nil {
+				return errors.Wrap(err, 0)
+			}
+			modulePath := modfile.ModulePath(data)
+			if modulePath == f.Module.Mod.Path || replaced[modulePath] {
+				continue
+			}
+			if err := f.AddReplace(modulePath, "", useDir, ""); err != nil {
+				return errors.Wrap(err, 0)
+			}
+			replaced[modulePath] = true
+		}
+		for _, r := range wf.Replace {
+			if replaced[r.Old.Path] {
+				continue
+			}
+			newPath := r.New.Path
+			// Replacements by directories start with ./ or ../
+			if r.New.Version == "" {
+				newPath = abs(newPath)
+			}
+			if err := f.AddReplace(r.Old.Path, r.Old.Version, newPath, r.New.Version); err != nil {
+				return errors.Wrap(err, 0)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,90 @@
+package cofaas
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/go-errors/errors"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Frontend parses protocol files into descriptors
+type Frontend string
+
+const (
+	// ProtocFrontend runs protoc which must be in the PATH unless
+	// its path is given by SetProtocPath
+	ProtocFrontend Frontend = "protoc"
+	// GoFrontend parses protocol files in-process
+	GoFrontend Frontend = "go"
+)
+
+// frontend is the frontend used by new generators
+var frontend = ProtocFrontend
+
+// SetFrontend selects the frontend parsing protocol files. protoc is
+// used by default.
+func SetFrontend(f Frontend) error {
+	switch f {
+	case ProtocFrontend, GoFrontend:
+		frontend = f
+		return nil
+	}
+	return errors.Errorf("unknown protocol frontend %s. Must be %s or %s", f, ProtocFrontend, GoFrontend)
+}
+
+// protocPath is the protoc executable run by the protoc frontend
+var protocPath = "protoc"
+
+// SetProtocPath sets the protoc executable run by the protoc frontend.
+// It is looked up in the PATH if it is not a path.
+func SetProtocPath(path string) {
+	protocPath = path
+}
+
+// ProtocVersion returns the version printed by the protoc executable
+// set by SetProtocPath, such as libprotoc 3.21.12
+func ProtocVersion() (string, error) {
+	out, err := exec.Command(protocPath, "--version").Output()
+	if err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// parseFiles parses the protocol files named in files which are
+// searched for in importPaths. The descriptors of the files and the
+// files they import are returned such that every file precedes the
+// files importing it.
+func parseFiles(importPaths []string, files []string) ([]*descriptorpb.FileDescriptorProto, error) {
+	c := protocompile.Compiler{
+		Resolver:       protocompile.WithStandardImports(&protocompile.SourceResolver{ImportPaths: importPaths}),
+		SourceInfoMode: protocompile.SourceInfoStandard,
+	}
+	parsed, err := c.Compile(context.Background(), files...)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	var res []*descriptorpb.FileDescriptorProto
+	seen := make(map[string]bool)
+	var add func(fd protoreflect.FileDescriptor)
+	add = func(fd protoreflect.FileDescriptor) {
+		if seen[fd.Path()] {
+			return
+		}
+		seen[fd.Path()] = true
+		for i := 0; i < fd.Imports().Len(); i++ {
+			add(fd.Imports().Get(i).FileDescriptor)
+		}
+		res = append(res, protodesc.ToFileDescriptorProto(fd))
+	}
+	for _, fd := range parsed {
+		add(fd)
+	}
+	return res, nil
+}
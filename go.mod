@@ -9,12 +9,16 @@ require (
 )
 
 require (
+	github.com/bufbuild/protocompile v0.6.0
 	github.com/otiai10/copy v1.12.0
 	github.com/sergi/go-diff v1.3.1
 	google.golang.org/protobuf v1.31.0
 )
 
-require github.com/stretchr/testify v1.8.4 // indirect
+require (
+	github.com/stretchr/testify v1.8.4 // indirect
+	golang.org/x/sync v0.3.0 // indirect
+)
 
 require (
 	github.com/go-errors/errors v1.5.0
@@ -1,14 +1,12 @@
 package metadata
 
 import (
-	"fmt"
 	"os"
 	"path"
 	"path/filepath"
 
 	"github.com/go-errors/errors"
 
-	opt "github.com/moznion/go-optional"
 	"gopkg.in/yaml.v3"
 )
 
@@ -24,20 +22,31 @@ type MetadataFile struct {
 		ProtoSpec `yaml:",inline"`
 		Role Role
 	} `yaml:"proto-map"`
+	// Directories searched for protocol files imported by the
+	// protocols
+	Includes []string `yaml:",omitempty"`
 }
 
 type ProtoSpec struct {
 	// The name of the protocol
 	Name   string
-	// The path of the protocol file
+	// The path of the protocol file. If Descriptor is given, the
+	// name of the protocol file in the descriptor set or empty to
+	// select its last file.
 	Path   string
+	// The path of a FileDescriptorSet defining the protocol
+	Descriptor string `yaml:",omitempty"`
 	// The go import path of the generated proto code
 	Import string
+	// The services of the export protocol to export. All services
+	// are exported if empty
+	Services []string `yaml:",omitempty"`
 }
 
 type Metadata struct {
-	ExportProto *ProtoSpec
-	ImportProto opt.Option[*ProtoSpec]
+	ExportProto  *ProtoSpec
+	ImportProtos []*ProtoSpec
+	Includes     []string
 }
 
 func Parse(file string, absolutify bool) (*Metadata, error) {
@@ -58,19 +67,31 @@ func Parse(file string, absolutify bool) (*Metadata, error) {
 
 	if absolutify {
 		for _, e := range *m.ProtoMap {
-			fmt.Println(e.Path)
+			if e.Descriptor != "" {
+				if !path.IsAbs(e.Descriptor) {
+					abs, err := filepath.Abs(filepath.Join(filepath.Dir(file), e.Descriptor))
+					if err != nil {
+						return nil, err
+					}
+					e.Descriptor = abs
+				}
+				if _, err := os.Stat(e.Descriptor); err != nil {
+					return nil, errors.Errorf("descriptor set %s does not exist", e.Descriptor)
+				}
+				// The protocol file is named by its name in the
+				// descriptor set
+				continue
+			}
+
 			if !path.IsAbs(e.Path) {
-				fmt.Println(file)
 				abs, err := filepath.Abs(file)
 				if err != nil {
 					return nil, err
 				}
-				fmt.Println(abs)
 				abspath, err := filepath.Abs(filepath.Join(filepath.Dir(abs), e.Path))
 				if err != nil {
 					return nil, err
 				}
-				fmt.Println(abspath)
 				e.Path = abspath
 			}
 
@@ -83,31 +104,54 @@ func Parse(file string, absolutify bool) (*Metadata, error) {
 			}
 		}
 
+		for i, inc := range m.Includes {
+			if !path.IsAbs(inc) {
+				abs, err := filepath.Abs(filepath.Join(filepath.Dir(file), inc))
+				if err != nil {
+					return nil, err
+				}
+				m.Includes[i] = abs
+			}
+			if stat, err := os.Stat(m.Includes[i]); err != nil || !stat.IsDir() {
+				return nil, errors.Errorf("include directory %s does not exist", m.Includes[i])
+			}
+		}
 	}
 
-	protos := make(map[string]ProtoSpec)
+	res := &Metadata{ImportProtos: []*ProtoSpec{}, Includes: m.Includes}
+	names := make(map[string]bool)
 	for _, e := range *m.ProtoMap {
-		protos[string(e.Role)] = ProtoSpec{
-			Name:   e.Name,
-			Path:   e.Path,
-			Import: e.Import,
+		spec := &ProtoSpec{
+			Name:       e.Name,
+			Path:       e.Path,
+			Descriptor: e.Descriptor,
+			Import:     e.Import,
+			Services:   e.Services,
 		}
-	}
+		if names[spec.Name] {
+			return nil, errors.Errorf("protocol %s is defined more than once in %s", spec.Name, file)
+		}
+		names[spec.Name] = true
 
-	var importMap opt.Option[*ProtoSpec] = nil
-	var exportMap *ProtoSpec
-	if val, ok := protos["export"]; ok {
-		exportMap = &val
-	} else {
-		return nil, errors.Errorf("protocol metadata does not define an export protocol")
+		switch e.Role {
+		case Export:
+			if res.ExportProto != nil {
+				return nil, errors.Errorf("protocol metadata defines more than one export protocol")
+			}
+			res.ExportProto = spec
+		case Import:
+			if len(spec.Services) > 0 {
+				return nil, errors.Errorf("services can only be selected for the export protocol but are given for %s", spec.Name)
+			}
+			res.ImportProtos = append(res.ImportProtos, spec)
+		default:
+			return nil, errors.Errorf("protocol %s has unknown role %s", spec.Name, e.Role)
+		}
 	}
 
-	if val, ok := protos["import"]; ok {
-		importMap = opt.Some(&val)
+	if res.ExportProto == nil {
+		return nil, errors.Errorf("protocol metadata does not define an export protocol")
 	}
 
-	return &Metadata{
-		ImportProto: importMap,
-		ExportProto: exportMap,
-	}, nil
+	return res, nil
 }
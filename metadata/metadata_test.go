@@ -4,7 +4,6 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
-	opt "github.com/moznion/go-optional"
 )
 
 func TestParse(t *testing.T) {
@@ -19,11 +18,93 @@ func TestParse(t *testing.T) {
 			Name:   string("helloworld"),
 			Path:   string("../../protos/helloworld.proto"),
 		},
-		ImportProto: opt.Some(&ProtoSpec{
+		ImportProtos: []*ProtoSpec{{
 			Import: string("cofaas_orig/protos/prodcon"),
 			Name:   string("prodcon"),
 			Path:   string("../../protos/prodcon.proto"),
-		}),
+		}},
+	}
+
+	if diff := cmp.Diff(*res, expected); diff != "" {
+		t.Fatalf("Expected and actual results differ\n%s", diff)
+	}
+}
+
+func TestParseMultipleImports(t *testing.T) {
+	res, err := Parse("testdata/multi.yaml", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Metadata{
+		ExportProto: &ProtoSpec{
+			Import:   "cofaas_orig/protos/frontend",
+			Name:     "frontend",
+			Path:     "../../protos/frontend.proto",
+			Services: []string{"Frontend", "FrontendAdmin"},
+		},
+		ImportProtos: []*ProtoSpec{{
+			Import: "cofaas_orig/protos/users",
+			Name:   "users",
+			Path:   "../../protos/users.proto",
+		}, {
+			Import: "cofaas_orig/protos/orders",
+			Name:   "orders",
+			Path:   "../../protos/orders.proto",
+		}, {
+			Import: "cofaas_orig/protos/billing",
+			Name:   "billing",
+			Path:   "../../protos/billing.proto",
+		}},
+	}
+
+	if diff := cmp.Diff(*res, expected); diff != "" {
+		t.Fatalf("Expected and actual results differ\n%s", diff)
+	}
+}
+
+func TestParseImportServices(t *testing.T) {
+	if _, err := Parse("testdata/import_services.yaml", false); err == nil {
+		t.Fatal("expected selecting services of an import protocol to fail")
+	}
+}
+
+func TestParseIncludes(t *testing.T) {
+	res, err := Parse("testdata/includes.yaml", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Metadata{
+		ExportProto: &ProtoSpec{
+			Import: "cofaas_orig/protos/orders",
+			Name:   "orders",
+			Path:   "../../protos/acme/orders/v1/orders.proto",
+		},
+		ImportProtos: []*ProtoSpec{},
+		Includes:     []string{"../../protos", "../../third_party/protos"},
+	}
+
+	if diff := cmp.Diff(*res, expected); diff != "" {
+		t.Fatalf("Expected and actual results differ\n%s", diff)
+	}
+}
+
+func TestParseDescriptor(t *testing.T) {
+	res, err := Parse("testdata/descriptor.yaml", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Metadata{
+		ExportProto: &ProtoSpec{
+			Import:     "cofaas_orig/protos/orders",
+			Name:       "orders",
+			Path:       "acme/orders/v1/orders.proto",
+			Descriptor: "../../build/orders.binpb",
+		},
+		ImportProtos: []*ProtoSpec{{
+			Import:     "cofaas_orig/protos/users",
+			Name:       "users",
+			Descriptor: "../../build/users.binpb",
+		}},
 	}
 
 	if diff := cmp.Diff(*res, expected); diff != "" {
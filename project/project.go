@@ -0,0 +1,115 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-errors/errors"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the name of the project file
+const FileName = "cofaas.yaml"
+
+// Config is the configuration of a cofaas project. Relative paths are
+// relative to the directory of the project file.
+type Config struct {
+	// Path of the implementation
+	Impl string `yaml:",omitempty"`
+	// Directories and descriptor sets searched for protocol files
+	// imported by the protocols
+	Includes []string `yaml:",omitempty"`
+	// Frontend parsing the protocol files
+	Frontend string `yaml:",omitempty"`
+	Output   Output
+	Wit      Wit
+	Stubs    Stubs
+	// Paths of the tools run by cofaas. Tools given by name are
+	// looked up in the PATH
+	Toolchain Toolchain
+}
+
+type Output struct {
+	// Directory the transformed modules are written to
+	Dir string `yaml:",omitempty"`
+}
+
+type Wit struct {
+	// Directory containing the WIT files. Generated from the
+	// protocols if empty
+	Path string `yaml:",omitempty"`
+	// The WIT world to generate a component for
+	World string `yaml:",omitempty"`
+}
+
+type Stubs struct {
+	// Version of the stub modules the gRPC packages are replaced with
+	Version string `yaml:",omitempty"`
+}
+
+type Toolchain struct {
+	Go         string `yaml:",omitempty"`
+	Protoc     string `yaml:",omitempty"`
+	WitBindgen string `yaml:"wit-bindgen,omitempty"`
+}
+
+// Find returns the path of the project file in dir or its closest
+// parent directory containing one. The empty string is returned if
+// there is none.
+func Find(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+	for {
+		file := filepath.Join(dir, FileName)
+		if stat, err := os.Stat(file); err == nil && !stat.IsDir() {
+			return file, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// Parse parses the project file and resolves its relative paths
+func Parse(file string) (*Config, error) {
+	f, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	c := &Config{}
+	if err := yaml.Unmarshal(f, c); err != nil {
+		return nil, errors.Errorf("failed to parse %s: %v", file, err)
+	}
+
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	dir := filepath.Dir(abs)
+	resolve := func(p *string) {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(dir, *p)
+		}
+	}
+	resolve(&c.Impl)
+	for i := range c.Includes {
+		resolve(&c.Includes[i])
+	}
+	resolve(&c.Output.Dir)
+	resolve(&c.Wit.Path)
+	for _, tool := range []*string{&c.Toolchain.Go, &c.Toolchain.Protoc, &c.Toolchain.WitBindgen} {
+		// Tools given by name are looked up in the PATH
+		if strings.ContainsRune(*tool, '/') {
+			resolve(tool)
+		}
+	}
+
+	return c, nil
+}
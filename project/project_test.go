@@ -0,0 +1,60 @@
+package project
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParse(t *testing.T) {
+	res, err := Parse("testdata/cofaas.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Config{
+		Impl:     filepath.Join(dir, "app/impl"),
+		Includes: []string{filepath.Join(dir, "protos"), "/usr/include"},
+		Frontend: "go",
+		Output:   Output{Dir: filepath.Join(dir, "build")},
+		Wit:      Wit{Path: filepath.Join(dir, "wit"), World: "shop"},
+		Stubs:    Stubs{Version: "v0.1.0"},
+		Toolchain: Toolchain{
+			Go:         "go",
+			Protoc:     filepath.Join(dir, "tools/protoc"),
+			WitBindgen: "/opt/bin/wit-bindgen",
+		},
+	}
+
+	if diff := cmp.Diff(*res, expected); diff != "" {
+		t.Fatalf("Expected and actual results differ\n%s", diff)
+	}
+}
+
+func TestFind(t *testing.T) {
+	expected, err := filepath.Abs("testdata/cofaas.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{"testdata", "testdata/nested/dir"} {
+		res, err := Find(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res != expected {
+			t.Errorf("expected to find %s from %s but got %s", expected, dir, res)
+		}
+	}
+
+	res, err := Find(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != "" {
+		t.Errorf("expected no project file but got %s", res)
+	}
+}
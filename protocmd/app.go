@@ -0,0 +1,197 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/go-errors/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const transformAppDescr = `Transforms the functions of an application to cofaas components
+
+Each implementation directory is a go module with its own protocol
+metadata and is transformed as by transform. The functions are named
+after their implementation directories. For an application with the
+functions a and b the following hierarchy is generated
+
+out
+| cofaas-app.yaml
+| protos -
+|        | ...
+| a -
+|   | component
+|   | impl
+|   | wit
+| b -
+|   | component
+|   | impl
+|   | wit
+
+A protocol module is generated once for each go_package of the
+protocols of the functions and shared by the functions using it.
+Copies of a protocol file in several implementations must be equal.
+
+cofaas-app.yaml lists the functions with the directories of their
+modules and the protocol modules they export and import. An import is
+annotated with the function exporting it if it is part of the
+application.
+
+The -update and -dry-run flags and the settings read from the project
+file are as for transform. The WIT packages of the functions are always
+generated from their protocols.`
+
+// appFile describes the layout of a transformed application
+const appFile = "cofaas-app.yaml"
+
+// appLayout is the contents of appFile
+type appLayout struct {
+	Functions []appFunction `yaml:"functions"`
+	// Directories of the protocol modules indexed by module name
+	Protos map[string]string `yaml:"protos"`
+}
+
+type appFunction struct {
+	Name      string      `yaml:"name"`
+	Component string      `yaml:"component"`
+	Impl      string      `yaml:"impl"`
+	Wit       string      `yaml:"wit"`
+	Export    string      `yaml:"export"`
+	Imports   []appImport `yaml:"imports,omitempty"`
+}
+
+type appImport struct {
+	Module string `yaml:"module"`
+	// The function of the application exporting the module
+	Function string `yaml:"function,omitempty"`
+}
+
+// functionNames returns the names of the functions implemented in
+// implPaths
+func functionNames(implPaths []string) ([]string, error) {
+	var names []string
+	seen := make(map[string]string)
+	for _, p := range implPaths {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+		name := filepath.Base(abs)
+		if name == "protos" {
+			return nil, usagef("implementation %s cannot be named protos", p)
+		}
+		if other, ok := seen[name]; ok {
+			return nil, usagef("implementations %s and %s have the same name %s", other, p, name)
+		}
+		seen[name] = p
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// doTransformApp transforms the implementations in implPaths to the
+// functions names of an application and writes the result to
+// outputDir
+func doTransformApp(names []string, implPaths []string, inputs protoInputs, outputDir string, update bool, dryRun bool) error {
+	return runTransformation(outputDir, update, dryRun, func(t *transformer) error {
+		var functions []*function
+		for i, implPath := range implPaths {
+			dir := path.Join(t.dir, names[i])
+			if err := os.Mkdir(dir, 0755); err != nil {
+				return errors.Wrap(err, 0)
+			}
+			f, err := t.addFunction(dir, implPath, inputs)
+			if err != nil {
+				return errors.Errorf("function %s: %v", names[i], err)
+			}
+			functions = append(functions, f)
+		}
+
+		if err := t.createProtoModules(); err != nil {
+			return errors.Wrap(err, 0)
+		}
+		for i, f := range functions {
+			if err := t.genFunction(f, "", ""); err != nil {
+				return errors.Errorf("function %s: %v", names[i], err)
+			}
+		}
+		return t.writeAppLayout(names, functions)
+	})
+}
+
+// writeAppLayout writes appFile describing the functions to the
+// directory of the transformation
+func (t *transformer) writeAppLayout(names []string, functions []*function) error {
+	exporters := make(map[string]string)
+	for i, f := range functions {
+		exporters[f.export.String()] = names[i]
+	}
+
+	layout := appLayout{Protos: make(map[string]string)}
+	for _, pm := range t.protoModules {
+		layout.Protos[pm.mod.name.String()] = pm.dir
+	}
+	for i, f := range functions {
+		fn := appFunction{
+			Name:      names[i],
+			Component: path.Join(names[i], "component"),
+			Impl:      path.Join(names[i], "impl"),
+			Wit:       path.Join(names[i], "wit"),
+			Export:    f.export.String(),
+		}
+		for _, im := range f.imports {
+			fn.Imports = append(fn.Imports, appImport{Module: im.String(), Function: exporters[im.String()]})
+		}
+		layout.Functions = append(layout.Functions, fn)
+	}
+
+	data, err := yaml.Marshal(layout)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	return os.WriteFile(filepath.Join(t.dir, appFile), data, 0644)
+}
+
+// setupTransformApp registers the flags of the transform-app command
+func setupTransformApp(fs *flag.FlagSet) func(args []string) error {
+	var inputs protoInputs
+	addIncludeFlag(fs, &inputs.includes)
+	outputDir := fs.String("outputDir", "", "The output directory")
+	update := fs.Bool("update", false, "Update the transformation in an existing output directory")
+	dryRun := fs.Bool("dry-run", false, "Print the modules, commands and rewrites of the transformation without writing the output directory. The implementation is still copied to and loaded from a temporary directory")
+	frontend := addFrontendFlag(fs)
+	config := addConfigFlag(fs)
+
+	return func(args []string) error {
+		if len(args) == 0 {
+			return usagef("at least one implementation directory must be given")
+		}
+		names, err := functionNames(args)
+		if err != nil {
+			return err
+		}
+		proj, err := loadProject(*config)
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+		set := flagsSet(fs)
+		setDefault(set, "outputDir", outputDir, proj.Output.Dir)
+		setDefault(set, "frontend", frontend, proj.Frontend)
+		inputs.includes = append(inputs.includes, proj.Includes...)
+		applyProject(proj)
+
+		if *outputDir == "" {
+			return usagef("flag outputDir or output.dir of the project file must be set")
+		}
+		if err := setFrontend(*frontend); err != nil {
+			return err
+		}
+		if _, err := os.Stat(*outputDir); err == nil && !*update {
+			return errors.Errorf("directory %v already exists. Specify a non-existent directory or -update", *outputDir)
+		}
+
+		return doTransformApp(names, args, inputs, *outputDir, *update, *dryRun)
+	}
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	c "github.com/truls/cofaas-go"
+)
+
+const cmdDescr = `Transforms go modules implementing gRPC services to cofaas components`
+
+// Exit codes of the commands
+const (
+	exitOK      = 0
+	exitFailure = 1
+	// The command was invoked with invalid flags or arguments
+	exitUsage = 2
+)
+
+type command struct {
+	name string
+	// args describes the arguments of the command
+	args string
+	// short is a one line description of the command
+	short string
+	// descr is the description shown in the help of the command
+	descr string
+	// setup registers the flags of the command and returns the
+	// function running it on its arguments
+	setup func(fs *flag.FlagSet) func(args []string) error
+}
+
+var commands = []*command{
+	{
+		name:  "transform",
+		short: "Transform a go module to a cofaas optimized module",
+		descr: transformDescr,
+		setup: setupTransform,
+	},
+	{
+		name:  "transform-app",
+		args:  "<implementation directories>",
+		short: "Transform the functions of an application sharing protocol modules",
+		descr: transformAppDescr,
+		setup: setupTransformApp,
+	},
+	{
+		name:  "gen",
+		args:  "proto|grpc|component|wit <protocol file> [import protocol files]",
+		short: "Run a single code generator on protocol files",
+		descr: genDescr,
+		setup: setupGen,
+	},
+	{
+		name:  "inspect",
+		short: "Print the resolved protocol metadata and package replacements",
+		descr: inspectDescr,
+		setup: setupInspect,
+	},
+	{
+		name:  "version",
+		short: "Print the versions of the tool and its code generators",
+		descr: versionDescr,
+		setup: setupVersion,
+	},
+}
+
+// usageError reports an invalid invocation of a command
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+func usagef(format string, args ...any) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+func usage(out io.Writer) {
+	fmt.Fprintf(out, "%s\n\nUsage: cofaas <command> [flags] [arguments]\n\nCommands:\n", cmdDescr)
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-10s %s\n", cmd.name, cmd.short)
+	}
+	fmt.Fprintf(out, "\nRun cofaas help <command> for the help of a command.\n")
+}
+
+// flagSet returns the flag set of the command whose usage prints the
+// help of the command
+func (cmd *command) flagSet() (*flag.FlagSet, func(args []string) error) {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	run := cmd.setup(fs)
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "Usage: cofaas %s [flags]", cmd.name)
+		if cmd.args != "" {
+			fmt.Fprintf(out, " %s", cmd.args)
+		}
+		fmt.Fprintf(out, "\n\n%s\n\nFlags:\n", cmd.descr)
+		fs.PrintDefaults()
+	}
+	return fs, run
+}
+
+// parseArgs parses the flags in args which may be interspersed with
+// the arguments of the command and returns the arguments
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var res []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return res, nil
+		}
+		res = append(res, args[0])
+		args = args[1:]
+	}
+}
+
+// run runs the command given by args and returns the exit code
+func run(args []string) int {
+	if len(args) == 0 {
+		usage(os.Stderr)
+		return exitUsage
+	}
+
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		if len(args) == 1 {
+			usage(os.Stdout)
+			return exitOK
+		}
+		cmd := findCommand(args[1])
+		if cmd == nil {
+			fmt.Fprintf(os.Stderr, "unknown command %s\n\n", args[1])
+			usage(os.Stderr)
+			return exitUsage
+		}
+		fs, _ := cmd.flagSet()
+		fs.SetOutput(os.Stdout)
+		fs.Usage()
+		return exitOK
+	}
+
+	cmd := findCommand(args[0])
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "unknown command %s\n\n", args[0])
+		usage(os.Stderr)
+		return exitUsage
+	}
+
+	fs, runCmd := cmd.flagSet()
+	cmdArgs, err := parseArgs(fs, args[1:])
+	if err == flag.ErrHelp {
+		return exitOK
+	} else if err != nil {
+		// The flag set has printed the error and the usage
+		return exitUsage
+	}
+
+	if err := runCmd(cmdArgs); err != nil {
+		if _, ok := err.(*usageError); ok {
+			fmt.Fprintf(os.Stderr, "%s\n\n", err)
+			fs.Usage()
+			return exitUsage
+		}
+		fmt.Fprintf(os.Stderr, "cofaas %s failed: %s\n", cmd.name, c.FormatError(err))
+		return exitFailure
+	}
+	return exitOK
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// protoFiles collects the values of a repeated flag
+type protoFiles []string
+
+func (p *protoFiles) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *protoFiles) Set(v string) error {
+	*p = append(*p, v)
+	return nil
+}
+
+// protoInputs are the protocols given on the command line
+type protoInputs struct {
+	exportProto          string
+	exportDescriptorSet  string
+	importProtos         []string
+	importDescriptorSets []string
+	includes             []string
+}
+
+// addProtoInputFlags registers the flags selecting the protocols of a
+// transformation
+func addProtoInputFlags(fs *flag.FlagSet) *protoInputs {
+	var in protoInputs
+	fs.StringVar(&in.exportProto, "exportProto", "", "The export protocol file name")
+	fs.Var((*protoFiles)(&in.importProtos), "importProto", "The import protocol file name. May be repeated")
+	fs.StringVar(&in.exportDescriptorSet, "exportDescriptorSet", "", "FileDescriptorSet defining the export protocol. Replaces exportProto")
+	fs.Var((*protoFiles)(&in.importDescriptorSets), "importDescriptorSet", "FileDescriptorSet defining an import protocol. May be repeated")
+	addIncludeFlag(fs, &in.includes)
+	return &in
+}
+
+// check validates the combination of protocol flags
+func (in *protoInputs) check() error {
+	if in.exportProto != "" && in.exportDescriptorSet != "" {
+		return usagef("only one of the flags exportProto and exportDescriptorSet may be set")
+	}
+	return nil
+}
+
+// checkFiles checks that the given protocol files exist
+func (in *protoInputs) checkFiles() error {
+	files := append(append([]string{}, in.importProtos...), in.importDescriptorSets...)
+	for _, f := range []string{in.exportProto, in.exportDescriptorSet} {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	for _, f := range files {
+		if _, err := os.Stat(f); os.IsNotExist(err) {
+			return usagef("file %v does not exist", f)
+		}
+	}
+	return nil
+}
+
+func addIncludeFlag(fs *flag.FlagSet, includes *[]string) {
+	fs.Var((*protoFiles)(includes), "I", "Directory or FileDescriptorSet searched for imported protocol files. May be repeated")
+}
+
+func addFrontendFlag(fs *flag.FlagSet) *string {
+	return fs.String("frontend", string(c.ProtocFrontend), "The frontend parsing the protocol files. Either protoc or go which does not require protoc")
+}
+
+func setFrontend(frontend string) error {
+	if err := c.SetFrontend(c.Frontend(frontend)); err != nil {
+		return usagef("%v", err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/go-errors/errors"
+	c "github.com/truls/cofaas-go"
+)
+
+const genDescr = `Runs a single code generator on protocol files and prints the
+generated code
+
+The generators are
+  proto      Go types of the messages and enums of the protocol
+  grpc       gRPC service definitions of the protocol
+  component  Glue code connecting the component to the gRPC implementation
+  wit        WIT package of the component
+
+The component and wit generators take the export protocol followed by
+the import protocols. Protocol files defined in a FileDescriptorSet
+given by -I are named by their name in the set.
+
+The includes, frontend and toolchain are read from the project file as
+by transform.`
+
+// setupGen registers the flags of the gen command
+func setupGen(fs *flag.FlagSet) func(args []string) error {
+	var includes []string
+	addIncludeFlag(fs, &includes)
+	var services protoFiles
+	fs.Var(&services, "service", "Service of the export protocol to export. May be repeated. All services are exported if not set")
+	output := fs.String("o", "", "The file the generated code is written to. Printed if not set")
+	frontend := addFrontendFlag(fs)
+	config := addConfigFlag(fs)
+
+	return func(args []string) error {
+		if len(args) < 2 {
+			return usagef("a generator and a protocol file must be given")
+		}
+		proj, err := loadProject(*config)
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+		setDefault(flagsSet(fs), "frontend", frontend, proj.Frontend)
+		includes = append(includes, proj.Includes...)
+		applyProject(proj)
+		if err := setFrontend(*frontend); err != nil {
+			return err
+		}
+
+		generator, files := args[0], args[1:]
+		var res string
+		switch generator {
+		case "proto", "grpc":
+			if len(files) != 1 {
+				return usagef("generator %s takes a single protocol file", generator)
+			}
+			if len(services) > 0 {
+				return usagef("services can only be selected for the component and wit generators")
+			}
+			if generator == "proto" {
+				res, err = c.GenProtoCode(files[0], includes)
+			} else {
+				res, err = c.GenGrpcCode(files[0], includes)
+			}
+		case "component":
+			res, err = c.GenComponentCode(files[0], files[1:], includes, services...)
+		case "wit":
+			res, err = c.GenWitCode(files[0], files[1:], includes, services...)
+		default:
+			return usagef("unknown generator %s", generator)
+		}
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+
+		if *output == "" {
+			fmt.Print(res)
+			return nil
+		}
+		if err := os.WriteFile(*output, []byte(res), 0644); err != nil {
+			return errors.Wrap(err, 0)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/go-errors/errors"
+	c "github.com/truls/cofaas-go"
+	"github.com/truls/cofaas-go/metadata"
+	"gopkg.in/yaml.v3"
+)
+
+const inspectDescr = `Prints the protocol metadata of the implementation with the protocol
+files resolved as by transform and the packages that the imports of
+the implementation are replaced with
+
+The settings are read from the project file as by transform.`
+
+// setupInspect registers the flags of the inspect command
+func setupInspect(fs *flag.FlagSet) func(args []string) error {
+	inputs := addProtoInputFlags(fs)
+	implPath := fs.String("implPath", "", "Path to the implementation")
+	frontend := addFrontendFlag(fs)
+	config := addConfigFlag(fs)
+
+	return func(args []string) error {
+		if len(args) > 0 {
+			return usagef("unexpected arguments %v", args)
+		}
+		if err := inputs.check(); err != nil {
+			return err
+		}
+		proj, err := loadProject(*config)
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+		set := flagsSet(fs)
+		setDefault(set, "implPath", implPath, proj.Impl)
+		setDefault(set, "frontend", frontend, proj.Frontend)
+		inputs.includes = append(inputs.includes, proj.Includes...)
+		applyProject(proj)
+
+		if *implPath == "" {
+			return usagef("flag implPath or impl of the project file must be set")
+		}
+		if err := setFrontend(*frontend); err != nil {
+			return err
+		}
+		if err := inputs.checkFiles(); err != nil {
+			return err
+		}
+
+		meta, err := c.LoadMetadata(*implPath)
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+		t := newTransfoermer()
+		t.includes = append(inputs.includes, meta.Includes...)
+		exportProto, importSpecs, importProtos, err := t.resolveProtocols(meta, *inputs)
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+
+		replacements := make(map[string]string)
+		for from, to := range pkgReplacements {
+			replacements[from] = to.Format()
+		}
+		if err := t.addProtoReplacements(replacements, meta.ExportProto, exportProto); err != nil {
+			return errors.Wrap(err, 0)
+		}
+		for i, spec := range importSpecs {
+			if err := t.addProtoReplacements(replacements, spec, importProtos[i]); err != nil {
+				return errors.Wrap(err, 0)
+			}
+		}
+
+		out := struct {
+			Metadata     *metadata.Metadata `yaml:"metadata"`
+			Replacements map[string]string  `yaml:"replacements"`
+		}{meta, replacements}
+		enc := yaml.NewEncoder(os.Stdout)
+		enc.SetIndent(2)
+		if err := enc.Encode(out); err != nil {
+			return errors.Wrap(err, 0)
+		}
+		return enc.Close()
+	}
+}
+
+// addProtoReplacements adds the replacements of the import path of
+// spec and the go_package import paths of protoFile and its imports to
+// replacements
+func (t *transformer) addProtoReplacements(replacements map[string]string, spec *metadata.ProtoSpec, protoFile string) error {
+	p, err := t.parseProtocol(protoFile, t.includes)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	importPath, err := p.GoImportPath()
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	replacements[spec.Import] = c.ProtoName(importPath).String()
+
+	deps, err := p.Dependencies()
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	importPaths := []string{importPath}
+	for _, d := range deps {
+		importPaths = append(importPaths, d.GoImportPath)
+	}
+	// The implementation may refer to the protocols by their own
+	// import paths
+	for _, p := range importPaths {
+		if _, ok := replacements[p]; !ok {
+			replacements[p] = c.ProtoName(p).String()
+		}
+	}
+	return nil
+}
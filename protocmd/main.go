@@ -1,22 +1,24 @@
 package main
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
 	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/go-errors/errors"
 	opt "github.com/moznion/go-optional"
-	cp "github.com/otiai10/copy"
 	c "github.com/truls/cofaas-go"
 	"github.com/truls/cofaas-go/metadata"
+	"github.com/truls/cofaas-go/protogen/witnames"
 )
 
-const cmdDescr = `Transforms a go module to a gofaas optimized module
+const transformDescr = `Transforms a go module to a gofaas optimized module
 
 For a go module in the directory a using proto b the following
 hierarchy is generated
@@ -43,7 +45,58 @@ a
 |      | a.go
 |      | ...
 |      | go.mod
-|      | go.sum`
+|      | go.sum
+| wit -
+|     | component.wit
+
+The wit directory is only generated when no WIT files are provided
+through -witPath
+
+The implementation may be a package inside a larger module. The
+package and the packages of the module it imports are then extracted
+into the impl module. Modules of a go.work workspace containing the
+implementation are replaced with their directories. Relative directory
+replacements of the go.mod of the implementation are made absolute and
+the replacements of its protocol packages are dropped.
+
+The implementation is type checked before it is rewritten. Uses of
+declarations of the packages replaced with stubs, such as net and
+grpc, that the stubs do not implement are reported with their
+positions.
+
+The protocols are given by the protocol metadata of the
+implementation. -exportProto and -importProto override the protocol
+files of the metadata.
+
+With -update, an existing output directory is updated. Only the files
+whose contents change are written and files generated by the previous
+transformation that are no longer generated are removed. Other files
+are left alone. The code of a protocol is only generated if the
+protocol or the files it imports changed, and the go commands of a
+module are skipped if its inputs, including the protocol modules it
+requires, are unchanged.
+
+With -dry-run, the modules that would be created, the commands that
+would be run and unified diffs of the rewritten implementation files
+are printed. Nothing is written to the output directory. The
+transformation is still prepared in a temporary directory, which is
+removed afterwards, as the rewrites are computed from a copy of the
+implementation that is loaded by the go toolchain.
+
+The settings are read from the project file cofaas.yaml which is
+searched for in the working directory and its parents unless -config
+is given. Flags override the settings of the project file.
+
+Protocol files imported by the protocols are searched for in the
+directories given by -I and the includes of the protocol metadata.
+A module is generated for each go_package of the imported files.
+
+Instead of .proto files, the protocols may be given as precompiled
+FileDescriptorSets, such as buf images, using -exportDescriptorSet and
+-importDescriptorSet or the descriptor field of the protocol metadata.
+The protocol file is the file of the set named by the path field of the
+metadata or the last file of the set. Its imports must be part of the
+set as well. Descriptor sets are not compiled by protoc.`
 
 var (
 	pkgVersion = opt.Some("v0.0.0-20230922142509-34101b6cc96a")
@@ -67,6 +120,37 @@ var (
 	}
 )
 
+func init() {
+	// Use the cofaas versions of the protobuf well-known types
+	for from, to := range c.WellKnownTypeReplacements() {
+		pkgReplacements[from] = &c.PkgSpec{
+			Name:    to,
+			Version: pkgVersion,
+			SubPkg:  true}
+	}
+}
+
+// The go and wit-bindgen executables. Looked up in the PATH unless
+// they are paths
+var (
+	goTool         = "go"
+	witBindgenTool = "wit-bindgen"
+)
+
+// setStubVersion sets the version of the stub modules that packages
+// are replaced with
+func setStubVersion(version string) {
+	pkgVersion = opt.Some(version)
+	for _, s := range pkgReplacements {
+		s.Version = pkgVersion
+	}
+	wellKnownTypesDep.version = pkgVersion
+}
+
+// wellKnownTypesDep is the module providing the packages that the
+// well-known types are replaced with
+var wellKnownTypesDep = goDep{importPath: c.WellKnownTypesModule, version: pkgVersion}
+
 type goDep struct {
 	// Import path of the dependency
 	// "github.com/truls/cofaas-go/stubs/grpc",
@@ -83,10 +167,88 @@ type goModule struct {
 	name         c.CofaasName
 	goExec       string
 	dependency   []goDep
+	// Hash of the inputs of the module. Computed on first use by
+	// inputsHash
+	inputHash string
+	// Set while the hash of the inputs is computed
+	hashing bool
+	// Set once restoring go.mod and go.sum has been attempted
+	restoreTried bool
+	// Directories outside the module whose files are inputs of the
+	// module
+	inputDirs []string
+	// Local modules the requirements of the module are replaced
+	// with. Their inputs are inputs of the module.
+	locals []*goModule
 }
 
 type transformer struct {
-	deferredGoCommands []*exec.Cmd
+	plan plan
+	// Only plan the transformation without running any commands
+	dryRun bool
+	// Directories searched for imported protocol files
+	includes []string
+	// The protocol modules indexed by the go_package import path of
+	// their protocol files
+	protoModules map[string]*protoModule
+	// Import paths of the protocol modules in the order they were
+	// added
+	protoOrder []string
+	// The protocol modules used by the function whose protocols are
+	// being added indexed by import path
+	used map[string]*protoModule
+	// Directory the transformation is generated in
+	dir string
+	// Directory the transformation is written to
+	outputDir string
+	// Manifest of the transformation in outputDir which is updated.
+	// nil unless updating.
+	previous *manifest
+	// Hashes of the inputs of the modules indexed by their directory
+	// relative to dir
+	moduleHashes map[string]string
+	// Hashes of the protocol files indexed by the path of their
+	// generated code relative to dir without suffix
+	protocolHashes map[string]string
+	// The parsed protocol files indexed by their path and includes
+	protocols map[string]*c.Protocol
+}
+
+// protoModule is a module containing the code generated for the
+// protocol files sharing a go_package
+type protoModule struct {
+	mod *goModule
+	// Directory of the module relative to the output directory
+	dir string
+	// Go import path of the protocol files
+	importPath string
+	// The protocol files
+	files []protoSource
+	// The protocol modules required by the module
+	requires []*protoModule
+}
+
+// protoSource is a protocol file of a protocol module
+type protoSource struct {
+	// Absolute path of the file or its name in a descriptor set
+	file string
+	// Directories and descriptor sets searched for the imports of the
+	// file
+	includes []string
+}
+
+// function is an implementation transformed to a component
+type function struct {
+	impl *implPacakge
+	// Directory the modules of the function are generated in
+	dir string
+	// Directories searched for imported protocol files
+	includes []string
+	// The protocol modules used by the function indexed by import path
+	protos map[string]*protoModule
+	// The modules of the export and import protocols
+	export  c.CofaasName
+	imports []c.CofaasName
 }
 
 type implPacakge struct {
@@ -97,55 +259,56 @@ type implPacakge struct {
 }
 
 func newTransfoermer() *transformer {
-	return &transformer{deferredGoCommands: []*exec.Cmd{}}
-}
-
-func (t *transformer) finalize() error {
-	for _, cmd := range t.deferredGoCommands {
-		if output, err := cmd.CombinedOutput(); err != nil {
-			return fmt.Errorf("failed to run command %s: %v, with output \n\n%s", cmd.String(), err, output)
-		}
+	return &transformer{
+		protoModules: make(map[string]*protoModule),
+		moduleHashes:   make(map[string]string),
+		protocolHashes: make(map[string]string),
+		protocols:      make(map[string]*c.Protocol),
 	}
-	return nil
 }
 
 func newGoModule(moduleName c.CofaasName, targetDir string, t *transformer) (*goModule, error) {
-	go_exec, err := exec.LookPath("go")
+	go_exec, err := exec.LookPath(goTool)
 	if err != nil {
 		return nil, fmt.Errorf("could not find go executable: %v", err)
 	}
 
-	return &goModule{
+	m := &goModule{
 		name:         moduleName,
 		dependency:   []goDep{},
 		replacements: make(map[string]string),
 		targetDir:    targetDir,
 		goExec:       go_exec,
 		transformer:  t,
-	}, nil
+	}
+	t.plan.modules = append(t.plan.modules, m)
+	return m, nil
 }
 
 func (m *goModule) writeFile(name string, contents string) error {
 	return os.WriteFile(path.Join(m.targetDir, name), []byte(contents), 0644)
 }
 
+// deferGoCommand plans running go with the specified arguments in
+// the working directory of the module after all other commands
 func (m *goModule) deferGoCommand(args ...string) {
 	gocmd := exec.Command(m.goExec, args...)
 	gocmd.Dir = m.targetDir
 	t := m.transformer
-	t.deferredGoCommands = append(t.deferredGoCommands, gocmd)
+	t.plan.deferred = append(t.plan.deferred, gocmd)
 }
 
-// Runs go with specified arguments in the working directory of the module
-func (m *goModule) runGoCommand(args ...string) error {
-	gocmd := exec.Command(m.goExec, args...)
-	gocmd.Dir = m.targetDir
-	//fmt.Println("Running command in " + m.targetDir)
-	if output, err := gocmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to run command %s: %v, with output \n\n%s", gocmd.String(), err, output)
-	}
+// addGoCommand plans running go with the specified arguments in the
+// working directory of the module
+func (m *goModule) addGoCommand(args ...string) {
+	m.addCommand(exec.Command(m.goExec, args...))
+}
 
-	return nil
+// addCommand plans running cmd in the working directory of the module
+func (m *goModule) addCommand(cmd *exec.Cmd) {
+	cmd.Dir = m.targetDir
+	t := m.transformer
+	t.plan.commands = append(t.plan.commands, cmd)
 }
 
 func (m *goModule) addReplacement(from c.CofaasName, to string) {
@@ -153,26 +316,36 @@ func (m *goModule) addReplacement(from c.CofaasName, to string) {
 }
 
 func (m *goModule) create() error {
-	if err := m.runGoCommand("mod", "init", m.name.String()); err != nil {
+	if restored, err := m.restoreModFiles(); err != nil {
 		return errors.Wrap(err, 0)
+	} else if restored {
+		return nil
 	}
 
+	m.addGoCommand("mod", "init", m.name.String())
+
 	return m.tidy()
 }
 
 func (m *goModule) tidy() error {
-	for k, v := range m.replacements {
-		if err := m.runGoCommand("mod", "edit",
-			fmt.Sprintf("-replace=%s=%s", k, v)); err != nil {
-			return errors.Wrap(err, 0)
-		}
+	if restored, err := m.restoreModFiles(); err != nil {
+		return errors.Wrap(err, 0)
+	} else if restored {
+		return nil
+	}
+
+	var replaced []string
+	for k := range m.replacements {
+		replaced = append(replaced, k)
+	}
+	sort.Strings(replaced)
+	for _, k := range replaced {
+		m.addGoCommand("mod", "edit", fmt.Sprintf("-replace=%s=%s", k, m.replacements[k]))
 	}
 
 	for _, d := range m.dependency {
 		version := d.version.TakeOr("v0.0.0")
-		if err := m.runGoCommand("mod", "edit", "-require", fmt.Sprintf("%s@%s", d.importPath, version)); err != nil {
-			return errors.Wrap(err, 0)
-		}
+		m.addGoCommand("mod", "edit", "-require", fmt.Sprintf("%s@%s", d.importPath, version))
 	}
 
 	// Tidy commands must be run after all modules have been generated
@@ -189,52 +362,275 @@ func getProtoBaseName(protoPath string) (string, error) {
 	return protoBaseName, nil
 }
 
-func (t *transformer) genProtoModule(moduleBase string, protoFile string) (c.CofaasName, error) {
-	moduleBase = path.Join(moduleBase, "protos")
-	stat, err := os.Stat(moduleBase)
+// addProtoModule returns the module for the protocol files with
+// go_package importPath. The module is created in the directory
+// protos/name unless a module for importPath already exists.
+func (t *transformer) addProtoModule(moduleBase string, name string, importPath string) (*protoModule, error) {
+	if pm, ok := t.protoModules[importPath]; ok {
+		t.markUsed(pm)
+		return pm, nil
+	}
+
+	protosDir := path.Join(moduleBase, "protos")
+	stat, err := os.Stat(protosDir)
 	if err == nil && !stat.IsDir() {
-		return "", fmt.Errorf("path %s exists but is not a directory", moduleBase)
+		return nil, fmt.Errorf("path %s exists but is not a directory", protosDir)
 	} else if os.IsNotExist(err) {
-		if err := os.Mkdir(moduleBase, 0755); err != nil {
-			return "", errors.Wrap(err, 0)
+		if err := os.Mkdir(protosDir, 0755); err != nil {
+			return nil, errors.Wrap(err, 0)
 		}
 	}
 
-	protoBaseName, err := getProtoBaseName(protoFile)
+	dir := path.Join("protos", name)
+	for _, pm := range t.protoModules {
+		// Protocols of different functions may share a name
+		if pm.dir == dir {
+			dir = path.Join("protos", depModuleName(importPath))
+			break
+		}
+	}
+	modulePath := path.Join(moduleBase, dir)
+	if err := os.Mkdir(modulePath, 0755); err != nil {
+		return nil, errors.Errorf("unable to create directory %s: %v", modulePath, err)
+	}
+
+	m, err := newGoModule(c.ProtoName(importPath), modulePath, t)
 	if err != nil {
-		return "", errors.Wrap(err, 0)
+		return nil, errors.Wrap(err, 0)
 	}
+	m.dependency = append(m.dependency, wellKnownTypesDep)
 
-	modulePath := path.Join(moduleBase, protoBaseName)
-	if err := os.Mkdir(modulePath, 0755); err != nil {
-		return "", errors.Errorf("unable to create directory %s: %v", modulePath, err)
+	pm := &protoModule{mod: m, dir: dir, importPath: importPath}
+	t.protoModules[importPath] = pm
+	t.protoOrder = append(t.protoOrder, importPath)
+	t.markUsed(pm)
+	return pm, nil
+}
+
+// markUsed records that the function whose protocols are being added
+// uses the module pm and the modules it requires
+func (t *transformer) markUsed(pm *protoModule) {
+	if t.used == nil || t.used[pm.importPath] != nil {
+		return
+	}
+	t.used[pm.importPath] = pm
+	for _, r := range pm.requires {
+		t.markUsed(r)
+	}
+}
+
+// addFile adds the protocol file to the module and reports whether it
+// was not already part of it. Files are given by their absolute path
+// or their name in a descriptor set. Files with the same name but
+// different paths are the same file if their contents are equal, such
+// as copies of a protocol file in the implementations of several
+// functions.
+func (pm *protoModule) addFile(file string, includes []string) (bool, error) {
+	for _, f := range pm.files {
+		if f.file == file {
+			return false, nil
+		}
+		if filepath.Base(f.file) != filepath.Base(file) {
+			continue
+		}
+		a, errA := os.ReadFile(f.file)
+		b, errB := os.ReadFile(file)
+		if errA != nil || errB != nil || !bytes.Equal(a, b) {
+			return false, errors.Errorf("protocol files %s and %s of go_package %s differ", f.file, file, pm.importPath)
+		}
+		return false, nil
+	}
+	pm.files = append(pm.files, protoSource{file: file, includes: includes})
+	return true, nil
+}
+
+// require makes the module depend on the module dep
+func (pm *protoModule) require(dep *protoModule) {
+	if dep == pm {
+		return
+	}
+	if _, ok := pm.mod.replacements[dep.mod.name.String()]; ok {
+		return
 	}
+	pm.mod.addReplacement(dep.mod.name, "../"+path.Base(dep.dir))
+	pm.mod.locals = append(pm.mod.locals, dep.mod)
+	pm.mod.dependency = append(pm.mod.dependency, goDep{importPath: dep.mod.name.String()})
+	pm.requires = append(pm.requires, dep)
+}
+
+// depModuleName returns the directory name of the module generated
+// for protocol files with go_package importPath that are imported by
+// other protocols
+func depModuleName(importPath string) string {
+	return strings.ReplaceAll(importPath, "/", "_")
+}
 
-	modName := c.ProtoNameBase.Ident(protoBaseName)
+// parseProtocol returns the protocol file parsed with includes. A file
+// is parsed once for every set of includes.
+func (t *transformer) parseProtocol(file string, includes []string) (*c.Protocol, error) {
+	key := strings.Join(append([]string{file}, includes...), "\x00")
+	if p, ok := t.protocols[key]; ok {
+		return p, nil
+	}
+	p, err := c.ParseProtocol(file, includes)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	t.protocols[key] = p
+	return p, nil
+}
 
-	m, err := newGoModule(modName, modulePath, t)
+// genProtoModule adds the protocol spec defined in protoFile and the
+// protocol files it imports to the protocol modules. The modules are
+// named after the go_package of the protocols. protoFile is the name of
+// the protocol file in its descriptor set if spec has one.
+func (t *transformer) genProtoModule(moduleBase string, spec *metadata.ProtoSpec, protoFile string) (c.CofaasName, error) {
+	if spec.Descriptor == "" {
+		abs, err := filepath.Abs(protoFile)
+		if err != nil {
+			return "", errors.Wrap(err, 0)
+		}
+		protoFile = abs
+	}
+	p, err := t.parseProtocol(protoFile, t.includes)
+	if err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+	importPath, err := p.GoImportPath()
 	if err != nil {
 		return "", errors.Wrap(err, 0)
 	}
+	pm, err := t.addProtoModule(moduleBase, spec.Name, importPath)
+	if err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+	if err := t.addProtoFile(moduleBase, pm, protoFile); err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+	return pm.mod.name, nil
+}
 
-	res, err := c.GenGrpcCode(protoFile)
+// addProtoFile adds file to the module pm and the protocol files it
+// imports to modules required by pm
+func (t *transformer) addProtoFile(moduleBase string, pm *protoModule, file string) error {
+	if added, err := pm.addFile(file, t.includes); err != nil {
+		return errors.Wrap(err, 0)
+	} else if !added {
+		return nil
+	}
+
+	// The dependencies include indirectly imported files which are
+	// required directly such that their replacements are in effect
+	p, err := t.parseProtocol(file, t.includes)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	deps, err := p.Dependencies()
 	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	for _, d := range deps {
+		dm, err := t.addProtoModule(moduleBase, depModuleName(d.GoImportPath), d.GoImportPath)
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+		pm.require(dm)
+		// Files defined in a descriptor set have no path
+		depFile := d.Name
+		if d.Path != "" {
+			if depFile, err = filepath.Abs(d.Path); err != nil {
+				return errors.Wrap(err, 0)
+			}
+		}
+		if err := t.addProtoFile(moduleBase, dm, depFile); err != nil {
+			return errors.Wrap(err, 0)
+		}
+	}
+	return nil
+}
+
+// createProtoModules generates the code of the protocol modules
+func (t *transformer) createProtoModules() error {
+	// The code of all modules is generated first since the inputs
+	// of a module include the modules it requires
+	for _, importPath := range t.protoOrder {
+		pm := t.protoModules[importPath]
+		for _, f := range pm.files {
+			if err := t.genProtoCode(pm, f); err != nil {
+				return errors.Wrap(err, 0)
+			}
+		}
+	}
+	for _, importPath := range t.protoOrder {
+		if err := t.protoModules[importPath].mod.create(); err != nil {
+			return errors.Wrap(err, 0)
+		}
+	}
+	return nil
+}
+
+// genProtoCode generates the code of the protocol file f in the module
+// pm. The code is restored from the output directory instead if the
+// protocol is unchanged.
+func (t *transformer) genProtoCode(pm *protoModule, f protoSource) error {
+	base := strings.TrimSuffix(filepath.Base(f.file), ".proto")
+	p, err := t.parseProtocol(f.file, f.includes)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	hash, err := p.Hash()
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	if restored, err := pm.mod.restoreProtoCode(base, hash); err != nil {
+		return errors.Wrap(err, 0)
+	} else if restored {
+		return nil
+	}
+
+	res, err := p.GrpcCode()
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	if res != "" {
+		if err := pm.mod.writeFile(base+"_grpc.pb.go", res); err != nil {
+			return errors.Wrap(err, 0)
+		}
+	}
+
+	res, err = p.ProtoCode()
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	if err := pm.mod.writeFile(base+".pb.go", res); err != nil {
+		return errors.Wrap(err, 0)
+	}
+	return nil
+}
+
+// genWit generates a WIT package for the protocols described by meta
+// and returns the directory containing it
+func (t *transformer) genWit(moduleBase string, meta *metadata.Metadata) (string, error) {
+	witDir := path.Join(moduleBase, "wit")
+	if err := os.Mkdir(witDir, 0755); err != nil {
 		return "", errors.Wrap(err, 0)
 	}
-	m.writeFile("grpc.go", res)
 
-	res, err = c.GenProtoCode(protoFile)
+	res, err := c.GenWitCode(meta.ExportProto.Path, importPaths(meta), t.includes, meta.ExportProto.Services...)
 	if err != nil {
 		return "", errors.Wrap(err, 0)
 	}
-	m.writeFile("proto.go", res)
 
-	return modName, m.create()
+	if err := os.WriteFile(path.Join(witDir, "component.wit"), []byte(res), 0644); err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+	return witDir, nil
 }
 
 func (t *transformer) genProtoComponent(
 	moduleBase string,
 	meta *metadata.Metadata,
+	protos map[string]*protoModule,
 	witPath string,
 	witWorld string) error {
 
@@ -247,47 +643,57 @@ func (t *transformer) genProtoComponent(
 	if err != nil {
 		return errors.Wrap(err, 0)
 	}
+	m.dependency = append(m.dependency, wellKnownTypesDep)
 
-	res, err := c.GenComponentCode(
-		meta.ExportProto.Path,
-		opt.Map(meta.ImportProto,
-			func(x *metadata.ProtoSpec) string { return x.Path }))
+	res, err := c.GenComponentCode(meta.ExportProto.Path, importPaths(meta), t.includes, meta.ExportProto.Services...)
 	if err != nil {
 		return errors.Wrap(err, 0)
 	}
-	m.writeFile("component.go", res)
+	if err := m.writeFile("component.go", res); err != nil {
+		return errors.Wrap(err, 0)
+	}
 
 	witPathAbs, err := filepath.Abs(witPath)
 	if err != nil {
 		return errors.Wrap(err, 0)
 	}
 	// Run wit-bindgen
-	witBindgen := exec.Command("wit-bindgen", "tiny-go", witPathAbs, "--world", witWorld, "--out-dir=gen")
-	witBindgen.Dir = moduleBase
-	if res, err := witBindgen.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to run wit-bindgen: %v\n\n%s", err, res)
-	}
+	m.addCommand(exec.Command(witBindgenTool, "tiny-go", witPathAbs, "--world", witWorld, "--out-dir=gen"))
+	m.inputDirs = append(m.inputDirs, witPathAbs)
 
-	m.addProtoReplacements(meta)
+	if err := m.addProtoReplacements(protos); err != nil {
+		return errors.Wrap(err, 0)
+	}
 	m.addReplacement(c.ImplName, "../impl")
 
 	return m.create()
 }
 
-// addProtoReplacements configures grpc replacement path based on
-// metadata derived from the module to be transformed
-func (m *goModule) addProtoReplacements(meta *metadata.Metadata) error {
-	ar := func(s *metadata.ProtoSpec) {
-		m.addReplacement(c.ProtoNameBase.Ident(s.Name), "../protos/"+s.Name)
+// importPaths returns the paths of the import protocols of meta
+func importPaths(meta *metadata.Metadata) []string {
+	var res []string
+	for _, s := range meta.ImportProtos {
+		res = append(res, s.Path)
 	}
-	ar(meta.ExportProto)
-	if meta.ImportProto.IsSome() {
-		ar(meta.ImportProto.Unwrap())
+	return res
+}
+
+// addProtoReplacements configures the replacement paths of the
+// protocol modules protos. The protocol modules must have been added
+// beforehand.
+func (m *goModule) addProtoReplacements(protos map[string]*protoModule) error {
+	for _, pm := range protos {
+		rel, err := filepath.Rel(m.targetDir, pm.mod.targetDir)
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+		m.addReplacement(pm.mod.name, filepath.ToSlash(rel))
+		m.locals = append(m.locals, pm.mod)
 	}
 	return nil
 }
 
-func (t *transformer) newImpl(dir string, pkgDir string, exportProt string, importProto opt.Option[string]) (*implPacakge, error) {
+func (t *transformer) newImpl(dir string, pkgDir string) (*implPacakge, error) {
 	rwr, err := c.NewPackageRewriter(pkgDir, dir)
 	if err != nil {
 		return nil, errors.Wrap(err, 0)
@@ -298,14 +704,20 @@ func (t *transformer) newImpl(dir string, pkgDir string, exportProt string, impo
 		return nil, errors.Wrap(err, 0)
 	}
 
-	m.addProtoReplacements(rwr.Metadata)
+	m.dependency = append(m.dependency, wellKnownTypesDep)
 
+	// The replacements of the protocol imports are specific to the
+	// implementation
+	replacements := make(c.PkgReplacement)
+	for from, to := range pkgReplacements {
+		replacements[from] = to
+	}
 
 	return &implPacakge{
 		mod:                  m,
 		meta:                 rwr.Metadata,
 		rwr:                  rwr,
-		protoPkgReplacements: pkgReplacements,
+		protoPkgReplacements: replacements,
 	}, nil
 }
 
@@ -315,145 +727,343 @@ func (i *implPacakge) addImportReplacement(im string, replacement string, versio
 		Version: version}
 }
 
-func (i *implPacakge) finalize() error {
+func (i *implPacakge) finalize(protos map[string]*protoModule) error {
+	if err := i.mod.addProtoReplacements(protos); err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	// Several imports may be replaced with the same module
+	required := make(map[string]*c.PkgSpec)
+	var names []string
 	for _, v := range i.protoPkgReplacements {
-		if ! v.SubPkg {
-			i.mod.runGoCommand("mod", "edit", "-require="+v.Format())
+		if _, ok := required[v.Name]; !ok && !v.SubPkg {
+			required[v.Name] = v
+			names = append(names, v.Name)
 		}
 	}
+	sort.Strings(names)
+	for _, name := range names {
+		i.mod.dependency = append(i.mod.dependency, goDep{importPath: name, version: required[name].Version})
+	}
 
 	if err := i.mod.tidy(); err != nil {
 		return errors.Wrap(err, 0)
 	}
 
+	t := i.mod.transformer
+	if t.dryRun {
+		diffs, err := i.rwr.Diff(i.protoPkgReplacements, t.dir)
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+		t.plan.diffs = append(t.plan.diffs, diffs...)
+		return nil
+	}
 	if err := i.rwr.Rewrite(i.protoPkgReplacements); err != nil {
 		return errors.Wrap(err, 0)
 	}
 	return nil
 }
 
-func doTransform(exportProto string, importProto opt.Option[string], outputDir string, witPath string, witWorld string, implPath string) error {
-	dir, err := os.MkdirTemp(os.TempDir(), "cofaas-transform")
-	fmt.Println(dir)
+// findImportSpec returns the import protocol of meta that is defined
+// in protoFile
+func findImportSpec(meta *metadata.Metadata, protoFile string) (*metadata.ProtoSpec, error) {
+	name, err := getProtoBaseName(protoFile)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	for _, s := range meta.ImportProtos {
+		if s.Name == name {
+			return s, nil
+		}
+	}
+	return nil, errors.Errorf("import protocol %s is not listed in the protocol metadata", name)
+}
+
+// useDescriptorSet makes spec refer to the file of the descriptor set
+// named by its path or the last file of the set
+func (t *transformer) useDescriptorSet(spec *metadata.ProtoSpec) error {
+	name, err := c.DescriptorSetFile(spec.Descriptor, spec.Path)
 	if err != nil {
 		return errors.Wrap(err, 0)
 	}
+	spec.Path = name
+	t.includes = append(t.includes, spec.Descriptor)
+	return nil
+}
 
-	// Remove temporary directory in case of failure
-	defer func() {
-		if err := os.RemoveAll(dir); err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+// resolveProtocols determines the protocol files of the export and
+// import protocols of meta. The protocol files and descriptor sets
+// given on the command line take precedence over the metadata and
+// replace the paths of its specs. It returns the export protocol file
+// and the import protocol files together with their specs.
+func (t *transformer) resolveProtocols(meta *metadata.Metadata, inputs protoInputs) (string, []*metadata.ProtoSpec, []string, error) {
+	export := meta.ExportProto
+	exportProto := inputs.exportProto
+	if exportProto != "" {
+		// The protocol file replaces the path and descriptor set
+		// of the metadata
+		export.Path = exportProto
+		export.Descriptor = ""
+	}
+	if inputs.exportDescriptorSet != "" {
+		if export.Descriptor == "" {
+			// The path refers to a .proto file
+			export.Path = ""
 		}
-	}()
+		export.Descriptor = inputs.exportDescriptorSet
+	}
+	if export.Descriptor != "" {
+		if err := t.useDescriptorSet(export); err != nil {
+			return "", nil, nil, errors.Wrap(err, 0)
+		}
+	}
+	exportProto = export.Path
+
+	var specs []*metadata.ProtoSpec
+	var files []string
+	for _, importProto := range inputs.importProtos {
+		spec, err := findImportSpec(meta, importProto)
+		if err != nil {
+			return "", nil, nil, errors.Wrap(err, 0)
+		}
+		spec.Path = importProto
+		spec.Descriptor = ""
+		specs = append(specs, spec)
+		files = append(files, importProto)
+	}
+	for _, set := range inputs.importDescriptorSets {
+		name, err := c.DescriptorSetFile(set, "")
+		if err != nil {
+			return "", nil, nil, errors.Wrap(err, 0)
+		}
+		spec, err := findImportSpec(meta, name)
+		if err != nil {
+			return "", nil, nil, errors.Wrap(err, 0)
+		}
+		spec.Path = name
+		spec.Descriptor = set
+		t.includes = append(t.includes, set)
+		specs = append(specs, spec)
+		files = append(files, name)
+	}
+	given := make(map[*metadata.ProtoSpec]bool)
+	for _, spec := range specs {
+		given[spec] = true
+	}
+	// The other import protocols are given by the metadata
+	for _, spec := range meta.ImportProtos {
+		if given[spec] {
+			continue
+		}
+		if spec.Descriptor != "" {
+			if err := t.useDescriptorSet(spec); err != nil {
+				return "", nil, nil, errors.Wrap(err, 0)
+			}
+		}
+		specs = append(specs, spec)
+		files = append(files, spec.Path)
+	}
+	return exportProto, specs, files, nil
+}
 
-	t := newTransfoermer()
+// addFunction copies the implementation in implPath to the directory
+// dir and adds the protocol modules of its protocols. The protocol
+// files and descriptor sets of inputs take precedence over the
+// protocol metadata of the implementation.
+func (t *transformer) addFunction(dir string, implPath string, inputs protoInputs) (*function, error) {
+	implPkg, err := t.newImpl(dir, implPath)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	t.includes = append(append([]string{}, inputs.includes...), implPkg.meta.Includes...)
 
-	implPkg, err := t.newImpl(dir, implPath, exportProto, importProto)
+	exportProto, importSpecs, importProtos, err := t.resolveProtocols(implPkg.meta, inputs)
 	if err != nil {
-		return errors.Wrap(err, 0)
+		return nil, errors.Wrap(err, 0)
 	}
 
-	if n, err := t.genProtoModule(dir, exportProto); err != nil {
-		return errors.Wrap(err, 0)
+	f := &function{impl: implPkg, dir: dir, protos: make(map[string]*protoModule)}
+	t.used = f.protos
+	defer func() { t.used = nil }()
+
+	if n, err := t.genProtoModule(t.dir, implPkg.meta.ExportProto, exportProto); err != nil {
+		return nil, errors.Wrap(err, 0)
 	} else {
 		implPkg.addImportReplacement(implPkg.meta.ExportProto.Import, n.String(), nil)
+		f.export = n
 	}
 
-	if importProto.IsSome() {
-		if n, err := t.genProtoModule(dir, importProto.Unwrap()); err != nil {
-			return errors.Wrap(err, 0)
+	for i, importProto := range importProtos {
+		spec := importSpecs[i]
+		if n, err := t.genProtoModule(t.dir, spec, importProto); err != nil {
+			return nil, errors.Wrap(err, 0)
 		} else {
-			implPkg.addImportReplacement(implPkg.meta.ImportProto.Unwrap().Import, n.String(), nil)
+			implPkg.addImportReplacement(spec.Import, n.String(), nil)
+			f.imports = append(f.imports, n)
 		}
 	}
 
-	if err := t.genProtoComponent(dir, implPkg.meta, witPath, witWorld); err != nil {
-		return errors.Wrap(err, 0)
+	// The implementation may refer to the imported protocol files by
+	// their own import paths
+	for _, pm := range f.protos {
+		if _, ok := implPkg.protoPkgReplacements[pm.importPath]; !ok {
+			implPkg.addImportReplacement(pm.importPath, pm.mod.name.String(), nil)
+		}
+	}
+
+	f.includes = t.includes
+	return f, nil
+}
+
+// genFunction generates the component of the function and rewrites
+// its implementation. The WIT package is generated from the protocols
+// unless witPath is given. The protocol modules must have been created
+// beforehand.
+func (t *transformer) genFunction(f *function, witPath string, witWorld string) error {
+	t.includes = f.includes
+
+	if witPath == "" {
+		var err error
+		if witPath, err = t.genWit(f.dir, f.impl.meta); err != nil {
+			return errors.Wrap(err, 0)
+		}
+		witWorld = witnames.World
 	}
 
-	if err := implPkg.finalize(); err != nil {
+	if err := t.genProtoComponent(f.dir, f.impl.meta, f.protos, witPath, witWorld); err != nil {
 		return errors.Wrap(err, 0)
 	}
 
-	// Run deferred go mod tidy commands
-	if err := t.finalize(); err != nil {
+	if err := f.impl.finalize(f.protos); err != nil {
 		return errors.Wrap(err, 0)
 	}
+	return nil
+}
 
-	// Finally move temporary directory to destination
+// runTransformation runs gen in a temporary directory and writes the
+// result to outputDir. gen adds the modules of the transformation to t
+// and generates them in t.dir. If update is set, an existing
+// transformation in outputDir is updated. If dryRun is set, the plan
+// of the transformation is printed instead.
+func runTransformation(outputDir string, update bool, dryRun bool, gen func(t *transformer) error) error {
 	absDir, err := filepath.Abs(outputDir)
 	if err != nil {
 		return errors.Wrap(err, 0)
 	}
 
-	return cp.Copy(dir, absDir)
-}
-
-func main() {
-	exportProto := flag.String("exportProto", "", "The export protocol file name")
-	importProto := flag.String("importProto", "", "The import protocol file name")
-	outputDir := flag.String("outputDir", "", "The output directory")
-	// compileComponent := flag.Bool("compileComponent", true, "Compile the wasm components")
-	// keepCode := flag.Bool("keepCode", true, "Keep the transformed code")
-	witPath := flag.String("witPath", "", "The directory containing wit files")
-	witWorld := flag.String("witWorld", "", "The WIT world to generate a component for")
-	implPath := flag.String("implPath", "", "Path to the implementation")
-	help := flag.Bool("help", false, "Prints help")
-	flag.Parse()
-
-	if *help {
-		fmt.Println(cmdDescr)
-		os.Exit(0)
+	dir, err := os.MkdirTemp(os.TempDir(), "cofaas-transform")
+	if err != nil {
+		return errors.Wrap(err, 0)
 	}
 
-	if *exportProto == "" {
-		fmt.Println("Flag protoFile must be set")
-		flag.Usage()
-		os.Exit(1)
-	}
+	// Remove temporary directory in case of failure
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}()
 
-	if *outputDir == "" {
-		fmt.Println("Flag outputDir must be set")
-		flag.Usage()
-		os.Exit(1)
+	t := newTransfoermer()
+	t.dir = dir
+	t.outputDir = absDir
+	t.dryRun = dryRun
+	if update {
+		if t.previous, err = readManifest(absDir); err != nil {
+			return errors.Wrap(err, 0)
+		}
 	}
 
-	if *witPath == "" {
-		fmt.Println("Flag witPath must be set")
-		flag.Usage()
-		os.Exit(1)
+	if err := gen(t); err != nil {
+		return errors.Wrap(err, 0)
 	}
 
-	if *witWorld == "" {
-		fmt.Println("Flag witWorld must be set")
-		flag.Usage()
-		os.Exit(1)
+	if dryRun {
+		t.plan.print(os.Stdout, dir)
+		return nil
 	}
 
-	if *implPath == "" {
-		fmt.Println("Flag implPath must be set")
-		flag.Usage()
-		os.Exit(1)
+	// Run the planned commands
+	if err := t.plan.execute(); err != nil {
+		return errors.Wrap(err, 0)
 	}
 
-	if _, err := os.Stat(*outputDir); err == nil {
-		fmt.Printf("DIrectory %v already exists. Specify a non-existant directory\n", *outputDir)
-		os.Exit(1)
-	}
+	// Finally move temporary directory to destination
+	return t.writeOutput(dir, absDir)
+}
 
-	if _, err := os.Stat(*exportProto); os.IsNotExist(err) {
-		fmt.Printf("File %v does not exists.\n", *exportProto)
-		os.Exit(1)
-	}
+// doTransform transforms the implementation in implPath and writes the
+// result to outputDir. If update is set, an existing transformation in
+// outputDir is updated. If dryRun is set, the plan of the
+// transformation is printed instead.
+func doTransform(inputs protoInputs, outputDir string, witPath string, witWorld string, implPath string, update bool, dryRun bool) error {
+	return runTransformation(outputDir, update, dryRun, func(t *transformer) error {
+		f, err := t.addFunction(t.dir, implPath, inputs)
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+		if err := t.createProtoModules(); err != nil {
+			return errors.Wrap(err, 0)
+		}
+		return t.genFunction(f, witPath, witWorld)
+	})
+}
 
-	ip := opt.Some(*importProto)
-	if *importProto == "" {
-		ip = opt.None[string]()
-	}
+// setupTransform registers the flags of the transform command
+func setupTransform(fs *flag.FlagSet) func(args []string) error {
+	inputs := addProtoInputFlags(fs)
+	outputDir := fs.String("outputDir", "", "The output directory")
+	// compileComponent := fs.Bool("compileComponent", true, "Compile the wasm components")
+	// keepCode := fs.Bool("keepCode", true, "Keep the transformed code")
+	witPath := fs.String("witPath", "", "The directory containing wit files. Generated from the protocols if not set")
+	witWorld := fs.String("witWorld", "", "The WIT world to generate a component for. Required if witPath is set")
+	implPath := fs.String("implPath", "", "Path to the implementation")
+	update := fs.Bool("update", false, "Update the transformation in an existing output directory")
+	dryRun := fs.Bool("dry-run", false, "Print the modules, commands and rewrites of the transformation without writing the output directory. The implementation is still copied to and loaded from a temporary directory")
+	frontend := addFrontendFlag(fs)
+	config := addConfigFlag(fs)
+
+	return func(args []string) error {
+		if len(args) > 0 {
+			return usagef("unexpected arguments %v", args)
+		}
+		if err := inputs.check(); err != nil {
+			return err
+		}
+		proj, err := loadProject(*config)
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+		set := flagsSet(fs)
+		setDefault(set, "witPath", witPath, proj.Wit.Path)
+		setDefault(set, "witWorld", witWorld, proj.Wit.World)
+		setDefault(set, "outputDir", outputDir, proj.Output.Dir)
+		setDefault(set, "implPath", implPath, proj.Impl)
+		setDefault(set, "frontend", frontend, proj.Frontend)
+		inputs.includes = append(inputs.includes, proj.Includes...)
+		applyProject(proj)
+
+		if *outputDir == "" {
+			return usagef("flag outputDir or output.dir of the project file must be set")
+		}
+		if *witPath != "" && *witWorld == "" {
+			return usagef("flag witWorld or wit.world of the project file must be set when witPath is set")
+		}
+		if *implPath == "" {
+			return usagef("flag implPath or impl of the project file must be set")
+		}
+		if err := setFrontend(*frontend); err != nil {
+			return err
+		}
+
+		if _, err := os.Stat(*outputDir); err == nil && !*update {
+			return errors.Errorf("directory %v already exists. Specify a non-existent directory or -update", *outputDir)
+		}
+		if err := inputs.checkFiles(); err != nil {
+			return err
+		}
 
-	if err := doTransform(*exportProto, ip, *outputDir, *witPath, *witWorld, *implPath); err != nil {
-		fmt.Printf("Generating go module failed %s\n", c.FormatError(err))
-		os.Exit(1)
+		return doTransform(*inputs, *outputDir, *witPath, *witWorld, *implPath, *update, *dryRun)
 	}
 }
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	c "github.com/truls/cofaas-go"
+	"github.com/truls/cofaas-go/metadata"
+)
+
+func TestResolveProtocolsOverride(t *testing.T) {
+	meta := &metadata.Metadata{
+		ExportProto: &metadata.ProtoSpec{Name: "helloworld", Path: "meta/helloworld.proto"},
+		ImportProtos: []*metadata.ProtoSpec{
+			{Name: "imports", Path: "meta/imports.proto"},
+			{Name: "users", Path: "meta/users.proto"},
+		},
+	}
+	inputs := protoInputs{
+		exportProto:  "../testdata/helloworld.proto",
+		importProtos: []string{"../testdata/imports.proto"},
+	}
+
+	tr := &transformer{}
+	exportProto, specs, files, err := tr.resolveProtocols(meta, inputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exportProto != inputs.exportProto {
+		t.Errorf("expected export protocol %s but got %s", inputs.exportProto, exportProto)
+	}
+	// The WIT and component code is generated from the paths of the
+	// specs
+	if meta.ExportProto.Path != inputs.exportProto {
+		t.Errorf("expected export spec path %s but got %s", inputs.exportProto, meta.ExportProto.Path)
+	}
+	expected := []string{"../testdata/imports.proto", "meta/users.proto"}
+	if diff := cmp.Diff(files, expected); diff != "" {
+		t.Errorf("Expected and actual import files differ\n%s", diff)
+	}
+	var paths []string
+	for _, s := range specs {
+		paths = append(paths, s.Path)
+	}
+	if diff := cmp.Diff(paths, expected); diff != "" {
+		t.Errorf("Expected and actual import spec paths differ\n%s", diff)
+	}
+}
+
+func TestParseProtocolOnce(t *testing.T) {
+	if err := c.SetFrontend(c.GoFrontend); err != nil {
+		t.Fatal(err)
+	}
+	defer c.SetFrontend(c.ProtocFrontend)
+
+	tr := newTransfoermer()
+	a, err := tr.parseProtocol("../testdata/imports.proto", []string{"../testdata/include"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := tr.parseProtocol("../testdata/imports.proto", []string{"../testdata/include"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b {
+		t.Errorf("expected the protocol to be parsed once")
+	}
+	b, err = tr.parseProtocol("../testdata/imports.proto", []string{"../testdata/include", "../testdata"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == b {
+		t.Errorf("expected the protocol to be parsed again with other includes")
+	}
+}
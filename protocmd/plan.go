@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// plan records the steps of a transformation. The commands are
+// recorded while the modules are generated and run once all modules
+// have been generated.
+type plan struct {
+	// The modules created by the transformation
+	modules  []*goModule
+	commands []*exec.Cmd
+	// Commands run after all other commands
+	deferred []*exec.Cmd
+	// Unified diffs of the rewritten implementation files
+	diffs []string
+}
+
+// execute runs the commands of the plan
+func (p *plan) execute() error {
+	for _, cmd := range append(p.commands, p.deferred...) {
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to run command %s: %v, with output \n\n%s", cmd.String(), err, output)
+		}
+	}
+	return nil
+}
+
+// print writes the plan to w. Directories are printed relative to the
+// directory dir the transformation is generated in.
+func (p *plan) print(w io.Writer, dir string) {
+	rel := func(d string) string {
+		if r, err := filepath.Rel(dir, d); err == nil {
+			return filepath.ToSlash(r)
+		}
+		return d
+	}
+
+	fmt.Fprintln(w, "Modules:")
+	for _, m := range p.modules {
+		fmt.Fprintf(w, "  %s in %s\n", m.name, rel(m.targetDir))
+	}
+
+	fmt.Fprintln(w, "\nCommands:")
+	for _, cmd := range append(p.commands, p.deferred...) {
+		args := append([]string{filepath.Base(cmd.Args[0])}, cmd.Args[1:]...)
+		fmt.Fprintf(w, "  (%s) %s\n", rel(cmd.Dir), strings.Join(args, " "))
+	}
+
+	if len(p.diffs) > 0 {
+		fmt.Fprintln(w, "\nRewritten files:")
+		for _, d := range p.diffs {
+			fmt.Fprint(w, d)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/go-errors/errors"
+	c "github.com/truls/cofaas-go"
+	"github.com/truls/cofaas-go/project"
+)
+
+func addConfigFlag(fs *flag.FlagSet) *string {
+	return fs.String("config", "", "The project file. "+project.FileName+" is searched for in the working directory and its parents if not set")
+}
+
+// loadProject returns the project configuration of the project file
+// config or the project file found from the working directory. The
+// empty configuration is returned if there is no project file.
+func loadProject(config string) (*project.Config, error) {
+	if config == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+		if config, err = project.Find(wd); err != nil {
+			return nil, errors.Wrap(err, 0)
+		} else if config == "" {
+			return &project.Config{}, nil
+		}
+	}
+	return project.Parse(config)
+}
+
+// flagsSet returns the names of the flags given on the command line
+func flagsSet(fs *flag.FlagSet) map[string]bool {
+	res := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		res[f.Name] = true
+	})
+	return res
+}
+
+// setDefault sets the value of the flag name to the project setting
+// value unless the flag is given on the command line
+func setDefault(set map[string]bool, name string, flagValue *string, value string) {
+	if !set[name] && value != "" {
+		*flagValue = value
+	}
+}
+
+// applyProject applies the toolchain and stub settings of the project
+func applyProject(proj *project.Config) {
+	if proj.Toolchain.Go != "" {
+		goTool = proj.Toolchain.Go
+	}
+	if proj.Toolchain.Protoc != "" {
+		c.SetProtocPath(proj.Toolchain.Protoc)
+	}
+	if proj.Toolchain.WitBindgen != "" {
+		witBindgenTool = proj.Toolchain.WitBindgen
+	}
+	if proj.Stubs.Version != "" {
+		setStubVersion(proj.Stubs.Version)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"path/filepath"
+	"testing"
+
+	c "github.com/truls/cofaas-go"
+)
+
+// TestProjectProtocols checks that the protocols of a project are
+// taken from the metadata of its implementation unless they are
+// overridden by flags
+func TestProjectProtocols(t *testing.T) {
+	proj, err := loadProject("testdata/project/cofaas.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	metaProto, err := filepath.Abs("../testdata/helloworld.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		args     []string
+		expected string
+	}{
+		{nil, metaProto},
+		{[]string{"-exportProto", "../testdata/helloworld_component.proto"}, "../testdata/helloworld_component.proto"},
+	} {
+		fs := flag.NewFlagSet("transform", flag.ContinueOnError)
+		inputs := addProtoInputFlags(fs)
+		if err := fs.Parse(test.args); err != nil {
+			t.Fatal(err)
+		}
+		meta, err := c.LoadMetadata(proj.Impl)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tr := &transformer{}
+		exportProto, _, _, err := tr.resolveProtocols(meta, *inputs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exportProto != test.expected || meta.ExportProto.Path != test.expected {
+			t.Errorf("%v: expected export protocol %s but got %s with spec path %s", test.args, test.expected, exportProto, meta.ExportProto.Path)
+		}
+	}
+}
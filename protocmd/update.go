@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
+
+	"github.com/go-errors/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// manifestFile records the files of a transformation in its output
+// directory
+const manifestFile = ".cofaas-manifest.yaml"
+
+// manifest describes the output of a transformation such that it can
+// be updated by later transformations
+type manifest struct {
+	// Hashes of the inputs of the generated modules indexed by their
+	// directory
+	Modules map[string]string `yaml:"modules"`
+	// Hashes of the generated files indexed by their path
+	Files map[string]string `yaml:"files"`
+	// Hashes of the protocol files indexed by the path of their
+	// generated code without suffix
+	Protocols map[string]string `yaml:"protocols,omitempty"`
+}
+
+// readManifest reads the manifest of the output directory. nil is
+// returned if there is none.
+func readManifest(outputDir string) (*manifest, error) {
+	data, err := os.ReadFile(filepath.Join(outputDir, manifestFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	m := &manifest{}
+	if err := yaml.Unmarshal(data, m); err != nil {
+		return nil, errors.Errorf("failed to parse %s: %v", manifestFile, err)
+	}
+	return m, nil
+}
+
+func hashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// inputsHash returns the hash of the inputs of the module which is
+// computed on first use
+func (m *goModule) inputsHash() (string, error) {
+	// The hash is empty for modules that require each other
+	if m.inputHash == "" && !m.hashing {
+		m.hashing = true
+		hash, err := m.hashInputs()
+		m.hashing = false
+		if err != nil {
+			return "", errors.Wrap(err, 0)
+		}
+		m.inputHash = hash
+	}
+	return m.inputHash, nil
+}
+
+// hashInputs returns a hash of the files of the module, the
+// replacements and dependencies added to its go.mod and the inputs of
+// the local modules it requires
+func (m *goModule) hashInputs() (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "module %s\n", m.name)
+
+	var replacements []string
+	for from, to := range m.replacements {
+		replacements = append(replacements, from+"="+to)
+	}
+	sort.Strings(replacements)
+	for _, r := range replacements {
+		fmt.Fprintf(h, "replace %s\n", r)
+	}
+	for _, d := range m.dependency {
+		fmt.Fprintf(h, "require %s@%s\n", d.importPath, d.version.TakeOr("v0.0.0"))
+	}
+
+	// go.mod and go.sum depend on the requirements of the local
+	// modules
+	locals := append([]*goModule{}, m.locals...)
+	sort.Slice(locals, func(i, j int) bool {
+		return locals[i].name.String() < locals[j].name.String()
+	})
+	for _, l := range locals {
+		hash, err := l.inputsHash()
+		if err != nil {
+			return "", errors.Wrap(err, 0)
+		}
+		fmt.Fprintf(h, "local %s %s\n", l.name, hash)
+	}
+
+	for i, dir := range append([]string{m.targetDir}, m.inputDirs...) {
+		// The module and the input directories generated with it
+		// are in a new directory every time
+		if i > 0 {
+			name := dir
+			if rel, err := filepath.Rel(m.transformer.dir, dir); err == nil && !strings.HasPrefix(rel, "..") {
+				name = filepath.ToSlash(rel)
+			}
+			fmt.Fprintf(h, "dir %s\n", name)
+		}
+		// WalkDir visits the files in lexical order
+		err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			data, err := os.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(dir, p)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(h, "file %s %s\n", filepath.ToSlash(rel), hashBytes(data))
+			return nil
+		})
+		if err != nil {
+			return "", errors.Wrap(err, 0)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// restoreModFiles restores go.mod and go.sum of the module from the
+// output directory if the inputs of the module are unchanged since the
+// transformation that produced it. Reports whether the files were
+// restored in which case the go commands of the module are skipped.
+func (m *goModule) restoreModFiles() (bool, error) {
+	if m.restoreTried {
+		// The files were restored when the module was created
+		return false, nil
+	}
+	m.restoreTried = true
+	hash, err := m.inputsHash()
+	if err != nil {
+		return false, errors.Wrap(err, 0)
+	}
+
+	t := m.transformer
+	rel, err := m.relDir()
+	if err != nil {
+		return false, errors.Wrap(err, 0)
+	}
+	t.moduleHashes[rel] = hash
+	if t.previous == nil || t.previous.Modules[rel] != hash {
+		return false, nil
+	}
+	return m.restoreFiles("go.mod", "go.sum")
+}
+
+// restoreProtoCode restores the code generated for the protocol file
+// base of the module from the output directory if the hash of the
+// protocol is unchanged since the transformation that produced it.
+// Reports whether the code was restored.
+func (m *goModule) restoreProtoCode(base string, hash string) (bool, error) {
+	t := m.transformer
+	rel, err := m.relDir()
+	if err != nil {
+		return false, errors.Wrap(err, 0)
+	}
+	key := rel + "/" + base
+	// The code also depends on the code generators of the tool
+	hash = hashBytes([]byte(toolVersion() + "\n" + hash))
+	t.protocolHashes[key] = hash
+	if t.previous == nil || t.previous.Protocols[key] != hash {
+		return false, nil
+	}
+	return m.restoreFiles(base+".pb.go", base+"_grpc.pb.go")
+}
+
+// toolVersion identifies the build of the tool
+func toolVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	version := info.Main.Version
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" || s.Key == "vcs.modified" {
+			version += " " + s.Value
+		}
+	}
+	return version
+}
+
+// relDir returns the directory of the module relative to the directory
+// of the transformation
+func (m *goModule) relDir() (string, error) {
+	rel, err := filepath.Rel(m.transformer.dir, m.targetDir)
+	if err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// restoreFiles copies the files of the module in names that were
+// generated by the previous transformation from the output directory.
+// The first file is required and nothing is restored unless it exists.
+func (m *goModule) restoreFiles(names ...string) (bool, error) {
+	t := m.transformer
+	rel, err := m.relDir()
+	if err != nil {
+		return false, errors.Wrap(err, 0)
+	}
+	files := make(map[string][]byte)
+	for _, name := range names {
+		if _, ok := t.previous.Files[rel+"/"+name]; !ok {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(t.outputDir, rel, name))
+		if err != nil {
+			// The files are generated from scratch if they have
+			// been removed
+			return false, nil
+		}
+		files[name] = data
+	}
+	if _, ok := files[names[0]]; !ok {
+		return false, nil
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(m.targetDir, name), data, 0644); err != nil {
+			return false, errors.Wrap(err, 0)
+		}
+	}
+	return true, nil
+}
+
+// writeOutput writes the transformation in dir to outputDir together
+// with its manifest. Only files whose contents differ from the files
+// in outputDir are written. Files generated by the previous
+// transformation that are no longer generated are removed while other
+// files in outputDir are left alone.
+func (t *transformer) writeOutput(dir string, outputDir string) error {
+	next := &manifest{Modules: t.moduleHashes, Files: make(map[string]string), Protocols: t.protocolHashes}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		next.Files[filepath.ToSlash(rel)] = hashBytes(data)
+
+		dst := filepath.Join(outputDir, rel)
+		if old, err := os.ReadFile(dst); err == nil && bytes.Equal(old, data) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(dst, data, info.Mode().Perm())
+	})
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	if t.previous != nil {
+		for rel := range t.previous.Files {
+			if _, ok := next.Files[rel]; ok {
+				continue
+			}
+			if err := removeStale(outputDir, rel); err != nil {
+				return errors.Wrap(err, 0)
+			}
+		}
+	}
+
+	data, err := yaml.Marshal(next)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	return os.WriteFile(filepath.Join(outputDir, manifestFile), data, 0644)
+}
+
+// removeStale removes the file rel of outputDir and the directories
+// containing it that become empty
+func removeStale(outputDir string, rel string) error {
+	p := filepath.Join(outputDir, filepath.FromSlash(rel))
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, 0)
+	}
+	for dir := filepath.Dir(p); strings.HasPrefix(dir, outputDir+string(filepath.Separator)); dir = filepath.Dir(dir) {
+		// Fails if the directory is not empty
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	c "github.com/truls/cofaas-go"
+)
+
+func TestHashLocalModules(t *testing.T) {
+	tr := newTransfoermer()
+	tr.dir = t.TempDir()
+	newModule := func(name string, locals ...*goModule) *goModule {
+		m := &goModule{
+			transformer:  tr,
+			name:         c.ProtoName(name),
+			targetDir:    filepath.Join(tr.dir, name),
+			replacements: make(map[string]string),
+			locals:       locals,
+		}
+		if err := os.Mkdir(m.targetDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	dep := newModule("dep")
+	m := newModule("app", dep)
+
+	hash := func(content string) string {
+		if err := os.WriteFile(filepath.Join(dep.targetDir, "dep.pb.go"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		dep.inputHash = ""
+		res, err := m.hashInputs()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+	if hash("package dep") == hash("package dep // changed") {
+		t.Errorf("expected the hash to change with the inputs of the local module")
+	}
+}
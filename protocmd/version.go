@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/go-errors/errors"
+	c "github.com/truls/cofaas-go"
+	"github.com/truls/cofaas-go/protogen/component/gencomponent"
+	"github.com/truls/cofaas-go/protogen/grpc/gengrpc"
+	"github.com/truls/cofaas-go/protogen/wit/genwit"
+)
+
+const versionDescr = `Prints the versions of the tool, its code generators, the stubs the
+gRPC packages are replaced with and protoc
+
+The toolchain and stub settings are read from the project file as by
+transform.`
+
+// setupVersion registers the flags of the version command
+func setupVersion(fs *flag.FlagSet) func(args []string) error {
+	config := addConfigFlag(fs)
+
+	return func(args []string) error {
+		if len(args) > 0 {
+			return usagef("unexpected arguments %v", args)
+		}
+		proj, err := loadProject(*config)
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+		applyProject(proj)
+
+		version, protobufVersion := "(unknown)", "(unknown)"
+		if info, ok := debug.ReadBuildInfo(); ok {
+			version = info.Main.Version
+			for _, d := range info.Deps {
+				if d.Path == "google.golang.org/protobuf" {
+					protobufVersion = d.Version
+				}
+			}
+		}
+
+		// protoc prints its version as libprotoc x.y.z
+		protocVersion := "protoc not found"
+		if v, err := c.ProtocVersion(); err == nil {
+			protocVersion = v
+		}
+
+		fmt.Printf("cofaas %s\n", version)
+		fmt.Printf("protoc-gen-cofaas-go-grpc %s\n", gengrpc.Version)
+		fmt.Printf("protoc-gen-cofaas-component %s\n", gencomponent.Version)
+		fmt.Printf("protoc-gen-cofaas-wit %s\n", genwit.Version)
+		fmt.Printf("protoc-gen-go (protobuf %s)\n", protobufVersion)
+		fmt.Printf("stubs %s\n", pkgVersion.Unwrap())
+		fmt.Println(protocVersion)
+		return nil
+	}
+}
@@ -1,17 +1,26 @@
 package cofaas
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"os/exec"
-	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
-	"unsafe"
+	"sync"
 
 	"github.com/go-errors/errors"
-	opt "github.com/moznion/go-optional"
 	cp "github.com/otiai10/copy"
+	"github.com/truls/cofaas-go/protogen/component/gencomponent"
+	"github.com/truls/cofaas-go/protogen/grpc/gengrpc"
+	gengo "github.com/truls/cofaas-go/protogen/types/internal_gengo"
+	"github.com/truls/cofaas-go/protogen/wit/genwit"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
 )
 
 var requireUnimplemented *bool
@@ -20,69 +29,214 @@ type generator struct {
 	dir      string
 	pkg_name string
 	fname    string
+	// Names of the other protocol files as seen by protoc
+	otherNames []string
+	// Directories searched for imported protocol files
+	includes []string
+	// Files of the descriptor sets given as includes indexed by name
+	setFiles map[string]*descriptorpb.FileDescriptorProto
+	// Names of the given protocol files which are defined in a
+	// descriptor set
+	fromSet map[string]bool
+	// Descriptors of the protocol files and their imports. Populated
+	// on first use.
+	files []*descriptorpb.FileDescriptorProto
+	// Frontend parsing the protocol files
+	frontend Frontend
 }
 
-type wrapperScript string
+// newGenerator returns a generator for file and otherFiles. includes
+// lists the directories and FileDescriptorSet files imported protocol
+// files are searched for in. Protocol files defined in a descriptor
+// set are given by their name in the set.
+func newGenerator(file string, otherFiles []string, includes []string) (*generator, error) {
+	if !strings.HasSuffix(file, ".proto") {
+		return nil, errors.Errorf("proto file %s must have suffix .proto", file)
+	}
+	gen := generator{frontend: frontend, fromSet: make(map[string]bool)}
+	var sets []string
+	for _, inc := range includes {
+		if isDescriptorSet(inc) {
+			sets = append(sets, inc)
+		} else {
+			gen.includes = append(gen.includes, inc)
+		}
+	}
+	setFiles, err := descriptorSetFiles(sets)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	gen.setFiles = setFiles
 
-func newWrapperScript(contents string) (wrapperScript, error) {
-	f, err := os.CreateTemp(os.TempDir(), "*")
+	dir, err := os.MkdirTemp("", "cofaas-protogen")
 	if err != nil {
-		return "", errors.Wrap(err, 0)
+		return nil, errors.Wrap(err, 0)
+	}
+	gen.dir = dir
+
+	if gen.fname, err = gen.addFile(file); err != nil {
+		gen.cleanup()
+		return nil, errors.Wrap(err, 0)
+	}
+	gen.pkg_name = strings.TrimSuffix(gen.fname, ".proto")
+
+	for _, f := range otherFiles {
+		name, err := gen.addFile(f)
+		if err != nil {
+			gen.cleanup()
+			return nil, errors.Wrap(err, 0)
+		}
+		gen.otherNames = append(gen.otherNames, name)
+	}
+
+	return &gen, err
+}
+
+// addFile makes file available to protoc and returns its name. Files
+// defined in a descriptor set keep their name. Files located in an
+// include directory are named relative to it such that imports of them
+// resolve to the same file. Other files are copied to the temporary
+// directory.
+func (g *generator) addFile(file string) (string, error) {
+	if _, ok := g.setFiles[file]; ok {
+		g.fromSet[file] = true
+		return file, nil
 	}
-	defer f.Close()
-	fname := f.Name()
-	if _, err := fmt.Fprintf(f, `#!/bin/bash
-exec %s
-`, contents); err != nil {
-		os.Remove(fname)
+	abs, err := filepath.Abs(file)
+	if err != nil {
 		return "", errors.Wrap(err, 0)
 	}
-	if err := os.Chmod(f.Name(), 0755); err != nil {
-		os.Remove(fname)
+	for _, inc := range g.includes {
+		incAbs, err := filepath.Abs(inc)
+		if err != nil {
+			return "", errors.Wrap(err, 0)
+		}
+		if rel, err := filepath.Rel(incAbs, abs); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel), nil
+		}
+	}
+
+	name := filepath.Base(file)
+	dst := filepath.Join(g.dir, name)
+	if _, err := os.Stat(dst); err == nil {
+		return "", errors.Errorf("protocol files must have distinct names but %s is given more than once", name)
+	}
+	if err := cp.Copy(file, dst); err != nil {
 		return "", errors.Wrap(err, 0)
 	}
-	return *(*wrapperScript)(unsafe.Pointer(&fname)), nil
+	return name, nil
 }
 
-func (s wrapperScript) string() string {
-	return string(s)
+// protoPaths returns the directories protocol files are searched for
+// in
+func (g *generator) protoPaths() []string {
+	return append([]string{g.dir}, g.includes...)
 }
 
-func (s wrapperScript) cleanup() {
-	os.Remove(string(s))
+// descriptors returns the descriptors of the protocol files given to
+// the generator and the files they import. Every file precedes the
+// files importing it.
+func (g *generator) descriptors() ([]*descriptorpb.FileDescriptorProto, error) {
+	if g.files != nil {
+		return g.files, nil
+	}
+	var names, setNames []string
+	for _, n := range append([]string{g.fname}, g.otherNames...) {
+		if g.fromSet[n] {
+			setNames = append(setNames, n)
+		} else {
+			names = append(names, n)
+		}
+	}
+	var files []*descriptorpb.FileDescriptorProto
+	if len(names) > 0 {
+		parsed, err := g.parse(names)
+		if err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+		files = parsed
+	}
+	// Files defined in descriptor sets are used as they are
+	fromSets, err := closeDescriptors(g.setFiles, setNames)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	g.files = mergeDescriptors(files, fromSets)
+	return g.files, nil
 }
 
-func newGenerator(file string, otherFile opt.Option[string]) (*generator, error) {
-	gen := generator{}
+// parse returns the descriptors of the protocol files named in names
+// and the files they import using the frontend of the generator
+func (g *generator) parse(names []string) ([]*descriptorpb.FileDescriptorProto, error) {
+	if g.frontend == GoFrontend {
+		return parseFiles(g.protoPaths(), names)
+	}
 
-	dir, err := os.MkdirTemp("", "cofass-protogen")
-	if err != nil {
+	descFile := filepath.Join(g.dir, "cofaas.desc")
+	var args []string
+	for _, p := range g.protoPaths() {
+		args = append(args, "-I"+p)
+	}
+	args = append(args,
+		"--include_imports",
+		"--include_source_info",
+		"--descriptor_set_out="+descFile)
+	args = append(args, names...)
+	if err := g.runProtoc(args...); err != nil {
 		return nil, errors.Wrap(err, 0)
 	}
-	gen.dir = dir
+	return readDescriptorSet(descFile)
+}
 
-	_, fname := filepath.Split(file)
-	gen.fname = fname
-	if !strings.HasSuffix(gen.fname, ".proto") {
-		return nil, errors.Wrap("proto file %a must have suffix .proto", 0)
+// descriptor returns the descriptor of the protocol file named name
+func (g *generator) descriptor(name string) (*descriptorpb.FileDescriptorProto, error) {
+	files, err := g.descriptors()
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
 	}
-	gen.pkg_name = strings.TrimSuffix(gen.fname, ".proto")
+	for _, f := range files {
+		if f.GetName() == name {
+			return f, nil
+		}
+	}
+	return nil, errors.Errorf("no descriptor of %s", name)
+}
 
-	if err := cp.Copy(file, filepath.Join(gen.dir, gen.fname)); err != nil {
-		return nil, errors.Wrap(err, 0)
+// protocolOpts returns the plugin options mapping the protocol files
+// processed by the generator to the cofaas modules containing their
+// code
+func (g *generator) protocolOpts() (string, error) {
+	files, err := g.descriptors()
+	if err != nil {
+		return "", errors.Wrap(err, 0)
 	}
-	if otherFile.IsSome() {
-		f := otherFile.Unwrap()
-		if err := cp.Copy(f, filepath.Join(gen.dir, filepath.Base(f))); err != nil {
-			return nil, errors.Wrap(err, 0)
+	opts := []string{wellKnownTypeOpts()}
+	for _, f := range files {
+		if _, ok := wellKnownTypes[f.GetName()]; ok {
+			continue
+		}
+		importPath, err := goImportPath(f)
+		if err != nil {
+			return "", errors.Wrap(err, 0)
 		}
+		opts = append(opts, "M"+f.GetName()+"="+ProtoName(importPath).String())
 	}
+	return strings.Join(opts, ","), nil
+}
 
-	return &gen, err
+// goImportPath returns the import path given by the go_package option
+// of file
+func goImportPath(file *descriptorpb.FileDescriptorProto) (string, error) {
+	// The package name may follow the import path separated by ;
+	importPath, _, _ := strings.Cut(file.GetOptions().GetGoPackage(), ";")
+	if importPath == "" {
+		return "", errors.Errorf("protocol %s does not have a go_package option", file.GetName())
+	}
+	return importPath, nil
 }
 
 func (*generator) runProtoc(args ...string) error {
-	protoc_path, err := exec.LookPath("protoc")
+	protoc_path, err := exec.LookPath(protocPath)
 	if err != nil {
 		return errors.Wrap(err, 0)
 	}
@@ -104,91 +258,352 @@ func (g *generator) getOutputFile(suffix string) string {
 	return g.pkg_name + suffix
 }
 
-func (g *generator) readOutput(fileName string) (string, error) {
-	res, err := os.ReadFile(filepath.Join(g.dir, fileName))
+func (g *generator) cleanup() error {
+	return os.RemoveAll(g.dir)
+}
+
+// plugin is a protoc plugin which is run in-process
+type plugin struct {
+	// paramFunc sets the parameters not handled by protogen
+	paramFunc func(name string, value string) error
+	run       func(gen *protogen.Plugin) error
+}
+
+func grpcPlugin() plugin {
+	p := gengrpc.New()
+	return plugin{paramFunc: p.Flags.Set, run: p.Run}
+}
+
+func typesPlugin() plugin {
+	return plugin{run: func(gen *protogen.Plugin) error {
+		for _, f := range gen.Files {
+			if f.Generate {
+				gengo.GenerateFile(gen, f)
+			}
+		}
+		gen.SupportedFeatures = gengo.SupportedFeatures
+		return nil
+	}}
+}
+
+func componentPlugin() plugin {
+	p := gencomponent.New()
+	return plugin{paramFunc: p.Flags.Set, run: p.Run}
+}
+
+func witPlugin() plugin {
+	p := genwit.New()
+	return plugin{paramFunc: p.Flags.Set, run: p.Run}
+}
+
+// runPlugin runs p on the protocol files of the generator with the
+// given parameters and returns the contents of the generated files
+// indexed by their names
+func (g *generator) runPlugin(p plugin, params ...string) (map[string]string, error) {
+	files, err := g.descriptors()
 	if err != nil {
-		return "", errors.Wrap(err, 0)
+		return nil, errors.Wrap(err, 0)
+	}
+	protocolOpts, err := g.protocolOpts()
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	params = append([]string{"paths=source_relative", protocolOpts}, params...)
+
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate:  append([]string{g.fname}, g.otherNames...),
+		Parameter:       proto.String(strings.Join(params, ",")),
+		ProtoFile:       files,
+		CompilerVersion: g.compilerVersion(),
+	}
+	gen, err := protogen.Options{ParamFunc: p.paramFunc}.New(req)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	if err := p.run(gen); err != nil {
+		gen.Error(err)
+	}
+	resp := gen.Response()
+	if resp.Error != nil {
+		return nil, errors.Errorf("failed to generate code for %s: %s", g.fname, resp.GetError())
 	}
 
-	return string(res), nil
+	res := make(map[string]string)
+	for _, f := range resp.File {
+		res[f.GetName()] = f.GetContent()
+	}
+	return res, nil
 }
 
-func (g *generator) cleanup() error {
-	return os.RemoveAll(g.dir)
+// output returns the contents of the generated file name
+func (g *generator) output(files map[string]string, name string) (string, error) {
+	res, ok := files[name]
+	if !ok {
+		return "", errors.Errorf("no file %s was generated for %s", name, g.fname)
+	}
+	return res, nil
 }
 
-func GenGrpcCode(file string) (string, error) {
-	g, err := newGenerator(file, nil)
+var protocVersion struct {
+	once    sync.Once
+	version *pluginpb.Version
+}
+
+// compilerVersion returns the version of protoc which is reported to
+// the plugins or nil if it is unknown or protoc is not used
+func (g *generator) compilerVersion() *pluginpb.Version {
+	if g.frontend != ProtocFrontend || len(g.fromSet) == 1+len(g.otherNames) {
+		return nil
+	}
+	protocVersion.once.Do(func() {
+		out, err := ProtocVersion()
+		if err != nil {
+			return
+		}
+		// The version is printed as libprotoc major.minor.patch[-suffix]
+		fields := strings.Fields(out)
+		if len(fields) != 2 {
+			return
+		}
+		version, suffix, _ := strings.Cut(fields[1], "-")
+		parts := strings.Split(version, ".")
+		if len(parts) != 3 {
+			return
+		}
+		var nums [3]int32
+		for i, p := range parts {
+			n, err := strconv.ParseInt(p, 10, 32)
+			if err != nil {
+				return
+			}
+			nums[i] = int32(n)
+		}
+		protocVersion.version = &pluginpb.Version{
+			Major:  proto.Int32(nums[0]),
+			Minor:  proto.Int32(nums[1]),
+			Patch:  proto.Int32(nums[2]),
+			Suffix: proto.String(suffix),
+		}
+	})
+	return protocVersion.version
+}
+
+// Protocol is a parsed protocol file. The code and information of the
+// protocol are derived from the descriptors parsed once by
+// ParseProtocol.
+type Protocol struct {
+	g *generator
+}
+
+// ParseProtocol parses file and the protocol files it imports which
+// are searched for in includes
+func ParseProtocol(file string, includes []string) (*Protocol, error) {
+	g, err := newGenerator(file, nil, includes)
 	if err != nil {
-		return "", errors.Wrap(err, 0)
+		return nil, errors.Wrap(err, 0)
 	}
+	// The temporary directory is only needed for parsing
 	defer g.cleanup()
-	ws, err := newWrapperScript("go run github.com/truls/cofaas-go/protogen/grpc")
+
+	if _, err := g.descriptors(); err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	return &Protocol{g: g}, nil
+}
+
+// Hash returns a hash of the descriptors of the protocol and the files
+// it imports and of the version of protoc reported to the code
+// generators. The code generated for the protocol only depends on
+// them and the generators.
+func (p *Protocol) Hash() (string, error) {
+	files, err := p.g.descriptors()
 	if err != nil {
 		return "", errors.Wrap(err, 0)
 	}
-	defer ws.cleanup()
+	h := sha256.New()
+	fmt.Fprintf(h, "file %s\n", p.g.fname)
+	for _, f := range files {
+		data, err := proto.MarshalOptions{Deterministic: true}.Marshal(f)
+		if err != nil {
+			return "", errors.Wrap(err, 0)
+		}
+		fmt.Fprintf(h, "descriptor %d\n", len(data))
+		h.Write(data)
+	}
+	if v := p.g.compilerVersion(); v != nil {
+		fmt.Fprintf(h, "compiler %d.%d.%d-%s\n", v.GetMajor(), v.GetMinor(), v.GetPatch(), v.GetSuffix())
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
 
-	if err := g.runProtoc("--plugin=protoc-gen-cofaas="+ws.string(),
-		"-I"+g.dir,
-		"--cofaas_opt=paths=source_relative",
-		"--cofaas_out="+g.dir,
-		g.fname); err != nil {
+// GrpcCode generates the gRPC service definitions of the protocol. The
+// empty string is returned if it defines no services.
+func (p *Protocol) GrpcCode() (string, error) {
+	files, err := p.g.runPlugin(grpcPlugin())
+	if err != nil {
 		return "", errors.Wrap(err, 0)
 	}
 
-	return g.readOutput(g.getOutputFile("_grpc.pb.go"))
+	return files[p.g.getOutputFile("_grpc.pb.go")], nil
 }
 
-func GenProtoCode(file string) (string, error) {
-	g, err := newGenerator(file, nil)
+// ProtoCode generates the Go types of the messages and enums of the
+// protocol
+func (p *Protocol) ProtoCode() (string, error) {
+	files, err := p.g.runPlugin(typesPlugin())
 	if err != nil {
 		return "", errors.Wrap(err, 0)
 	}
-	defer g.cleanup()
-	ws, err := newWrapperScript("go run github.com/truls/cofaas-go/protogen/types")
+
+	return p.g.output(files, p.g.getOutputFile(".pb.go"))
+}
+
+// GenGrpcCode generates the gRPC service definitions of file. Imported
+// protocol files are searched for in includes. The empty string is
+// returned if file defines no services.
+func GenGrpcCode(file string, includes []string) (string, error) {
+	p, err := ParseProtocol(file, includes)
 	if err != nil {
 		return "", errors.Wrap(err, 0)
 	}
-	defer ws.cleanup()
+	return p.GrpcCode()
+}
 
-	if err := g.runProtoc("--plugin=protoc-gen-cofaas="+ws.string(),
-		"-I"+g.dir,
-		"--cofaas_opt=paths=source_relative",
-		"--cofaas_out="+g.dir,
-		g.fname); err != nil {
+// GenProtoCode generates the Go types of the messages and enums of
+// file. Imported protocol files are searched for in includes.
+func GenProtoCode(file string, includes []string) (string, error) {
+	p, err := ParseProtocol(file, includes)
+	if err != nil {
 		return "", errors.Wrap(err, 0)
 	}
+	return p.ProtoCode()
+}
 
-	return g.readOutput(g.getOutputFile(".pb.go"))
+// ProtoFile describes a protocol file imported by another protocol
+type ProtoFile struct {
+	// Name of the file relative to the include directory it was
+	// found in or its name in a descriptor set
+	Name string
+	// Path of the file. Empty if the file is defined in a
+	// descriptor set.
+	Path string
+	// Import path given by the go_package option of the file
+	GoImportPath string
 }
 
-func GenComponentCode(exportFile string, importFile opt.Option[string]) (string, error) {
-	g, err := newGenerator(exportFile, importFile)
+// GoImportPath returns the import path given by the go_package option
+// of the protocol
+func (p *Protocol) GoImportPath() (string, error) {
+	f, err := p.g.descriptor(p.g.fname)
 	if err != nil {
 		return "", errors.Wrap(err, 0)
 	}
-	defer g.cleanup()
+	return goImportPath(f)
+}
 
-	ws, err := newWrapperScript("go run github.com/truls/cofaas-go/protogen/component")
+// GoImportPath returns the import path given by the go_package option
+// of the protocol defined in file
+func GoImportPath(file string, includes []string) (string, error) {
+	p, err := ParseProtocol(file, includes)
 	if err != nil {
 		return "", errors.Wrap(err, 0)
 	}
-	defer ws.cleanup()
-	fileArgs := []string{
-		"--plugin=protoc-gen-cofaas=" + ws.string(),
-		"-I" + g.dir,
-		"--cofaas_opt=paths=source_relative",
-		"--cofaas_out=" + g.dir,
-		g.fname}
+	return p.GoImportPath()
+}
 
-	if importFile.IsSome() {
-		fileArgs = append(fileArgs, path.Base(importFile.Unwrap()))
+// ProtoDependencies returns the protocol files imported directly or
+// indirectly by file. The files are found in includes and returned
+// such that every file precedes the files importing it. The
+// well-known types are omitted.
+func ProtoDependencies(file string, includes []string) ([]*ProtoFile, error) {
+	p, err := ParseProtocol(file, includes)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
 	}
+	return p.Dependencies()
+}
 
-	if err := g.runProtoc(fileArgs...); err != nil {
+// Dependencies returns the protocol files imported directly or
+// indirectly by the protocol as ProtoDependencies does
+func (p *Protocol) Dependencies() ([]*ProtoFile, error) {
+	g := p.g
+	files, err := g.descriptors()
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	var res []*ProtoFile
+	for _, f := range files {
+		if _, ok := wellKnownTypes[f.GetName()]; ok || f.GetName() == g.fname {
+			continue
+		}
+		importPath, err := goImportPath(f)
+		if err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+		// The imports of a file from a descriptor set are defined
+		// in the descriptor sets as well
+		var path string
+		if !g.fromSet[g.fname] {
+			if path, err = findInclude(f.GetName(), g.includes); err != nil {
+				return nil, errors.Wrap(err, 0)
+			}
+		}
+		res = append(res, &ProtoFile{
+			Name:         f.GetName(),
+			Path:         path,
+			GoImportPath: importPath,
+		})
+	}
+	return res, nil
+}
+
+// findInclude returns the path of the first file named name in the
+// include directories
+func findInclude(name string, includes []string) (string, error) {
+	for _, inc := range includes {
+		p := filepath.Join(inc, filepath.FromSlash(name))
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		}
+	}
+	return "", errors.Errorf("imported protocol %s not found in include directories %v", name, includes)
+}
+
+// genExportImportCode runs p on exportFile and importFiles and
+// returns the contents of outputFile. Only the services of exportFile
+// named in exportServices are exported, or all if it is empty.
+// Imported protocol files are searched for in includes.
+func genExportImportCode(p plugin, outputFile string, exportFile string, importFiles []string, includes []string, exportServices []string) (string, error) {
+	g, err := newGenerator(exportFile, importFiles, includes)
+	if err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+	defer g.cleanup()
+
+	var opts []string
+	for _, s := range exportServices {
+		opts = append(opts, "services="+s)
+	}
+	files, err := g.runPlugin(p, opts...)
+	if err != nil {
 		return "", errors.Wrap(err, 0)
 	}
 
-	return g.readOutput("component.go")
+	return g.output(files, outputFile)
+}
+
+// GenComponentCode generates the glue code connecting the wit-bindgen
+// bindings of the component to the gRPC implementation. Imported
+// protocol files are searched for in includes.
+func GenComponentCode(exportFile string, importFiles []string, includes []string, exportServices ...string) (string, error) {
+	return genExportImportCode(componentPlugin(), "component.go", exportFile, importFiles, includes, exportServices)
+}
+
+// GenWitCode generates a WIT package containing a world that exports
+// the services defined in exportFile and imports the services defined
+// in importFiles. If exportServices is given, only the services named
+// in it are exported. Imported protocol files are searched for in
+// includes.
+func GenWitCode(exportFile string, importFiles []string, includes []string, exportServices ...string) (string, error) {
+	return genExportImportCode(witPlugin(), "component.wit", exportFile, importFiles, includes, exportServices)
 }
@@ -0,0 +1,336 @@
+package gencomponent
+
+import (
+	"fmt"
+
+	"github.com/truls/cofaas-go/protogen/witnames"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	contextPackage = protogen.GoImportPath("context")
+	errorsPackage  = protogen.GoImportPath("errors")
+	fmtPackage     = protogen.GoImportPath("fmt")
+	implPackage    = protogen.GoImportPath("cofaas/application/impl")
+	ioPackage      = protogen.GoImportPath("io")
+	sortPackage    = protogen.GoImportPath("sort")
+	syncPackage    = protogen.GoImportPath("sync")
+	// componentPackage is the import path of the generated code
+	componentPackage = protogen.GoImportPath("cofaas/application/component")
+)
+
+// FileDescriptorProto.package field number
+const fileDescriptorProtoPackageFieldNumber = 2
+
+// FileDescriptorProto.syntax field number
+const fileDescriptorProtoSyntaxFieldNumber = 12
+
+// generateFile generates a _grpc.pb.go file containing gRPC service definitions.
+func GenerateFile(gen *protogen.Plugin, exportFile *protogen.File, services []string, importFiles []*protogen.File) *protogen.GeneratedFile {
+	exportSvcs, err := witnames.SelectServices(exportFile, services)
+	if err != nil {
+		gen.Error(err)
+		return nil
+	}
+	var importSvcs []*protogen.Service
+	for _, importFile := range importFiles {
+		svcs, err := witnames.SelectServices(importFile, nil)
+		if err != nil {
+			gen.Error(err)
+			return nil
+		}
+		importSvcs = append(importSvcs, svcs...)
+	}
+	if err := checkServiceNames(append(append([]*protogen.Service{}, exportSvcs...), importSvcs...)); err != nil {
+		gen.Error(err)
+		return nil
+	}
+
+	filename := "component.go"
+	g := gen.NewGeneratedFile(filename, componentPackage)
+	// Attach all comments associated with the syntax field.
+	//genLeadingComments(g, file.Desc.SourceLocations().ByPath(protoreflect.SourcePath{fileDescriptorProtoSyntaxFieldNumber}))
+	// g.P("// Code generated by protoc-gen-cofaas-go-grpc. DO NOT EDIT.")
+	// g.P("// versions:")
+	// g.P("// - protoc-gen-cofaas-go-grpc v", version)
+	// g.P("// - protoc             ", protocVersion(gen))
+	// if file.Proto.GetOptions().GetDeprecated() {
+	// 	g.P("// ", file.Desc.Path(), " is a deprecated file.")
+	// } else {
+	// 	g.P("// source: ", file.Desc.Path())
+	// }
+	// g.P()
+	// Attach all comments associated with the package field.
+	//genLeadingComments(g, file.Desc.SourceLocations().ByPath(protoreflect.SourcePath{fileDescriptorProtoPackageFieldNumber}))
+	g.P("package main")
+	g.P()
+	generateFileContent(gen, exportSvcs, importSvcs, g)
+	return g
+}
+
+// checkServiceNames asserts that the names of the services are
+// distinct since the generated identifiers are derived from them
+func checkServiceNames(svcs []*protogen.Service) error {
+	seen := make(map[string]bool)
+	for _, svc := range svcs {
+		name := witnames.Ident(svc.GoName)
+		if seen[name] {
+			return fmt.Errorf("more than one service is named %s", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+func generateFileContent(gen *protogen.Plugin, exportSvcs []*protogen.Service, importSvcs []*protogen.Service, g *protogen.GeneratedFile) {
+	for _, svc := range exportSvcs {
+		genExportStructDecl(svc, g)
+	}
+	for _, svc := range importSvcs {
+		genImportStructDecl(svc, g)
+	}
+	g.P()
+
+	genInitFunc(gen, exportSvcs, importSvcs, g)
+	g.P()
+
+	// Generate handlers for export functions
+	genInitComponent(exportSvcs, importSvcs, g)
+	g.P()
+
+	var exportConvs []*witConverter
+	for _, svc := range exportSvcs {
+		exportConv := newWitConverter(serviceFile(gen, svc), svc)
+		genExportHandlers(gen, svc, exportConv, g)
+		g.P()
+		exportConvs = append(exportConvs, exportConv)
+	}
+
+	// Generate handlers for import functions
+	var importConvs []*witConverter
+	for _, svc := range importSvcs {
+		importConv := newWitConverter(serviceFile(gen, svc), svc)
+		genImportHandlers(gen, svc, importConv, g)
+		g.P()
+		importConvs = append(importConvs, importConv)
+	}
+
+	// Generate functions converting messages to and from WIT types
+	for _, exportConv := range exportConvs {
+		exportConv.genFunctions(g)
+	}
+	for _, importConv := range importConvs {
+		importConv.genFunctions(g)
+	}
+
+	g.P("//go:generate wit-bindgen tiny-go ../wit --world " + witnames.World + " --out-dir=gen")
+	g.P("func main() {}")
+
+}
+
+func genExportStructName(svc *protogen.Service) string {
+	return unexport(svc.GoName) + "Impl"
+}
+
+func genImportStructName(svc *protogen.Service) string {
+	return unexport(svc.GoName) + "ClientImpl"
+}
+
+func genExportStructDecl(svc *protogen.Service, g *protogen.GeneratedFile) {
+	g.P("type " + genExportStructName(svc) + " struct{}")
+}
+
+func genImportStructDecl(svc *protogen.Service, g *protogen.GeneratedFile) {
+	g.P("type " + genImportStructName(svc) + " struct{}")
+}
+
+func getInterfaceIdent(ident string, g *protogen.GeneratedFile) string {
+	return g.QualifiedGoIdent(protogen.GoIdent{
+		GoName:       ident,
+		GoImportPath: "cofaas/application/component/gen",
+	})
+}
+
+// getWitIdent returns the qualified identifier generated by
+// wit-bindgen for name in the WIT interface of svc
+func getWitIdent(svc *protogen.Service, name string, g *protogen.GeneratedFile) string {
+	return getInterfaceIdent(witnames.InterfaceGoName(witnames.Ident(svc.GoName))+witnames.GoName(witnames.Ident(name)), g)
+}
+
+// getWitMethodName returns the name of the Go method generated by
+// wit-bindgen for method
+func getWitMethodName(method *protogen.Method) string {
+	return witnames.GoName(witnames.Ident(method.GoName))
+}
+
+// getProtoIdent returns the qualified identifier ident of the code
+// generated for file. The import paths of the protocols are expected
+// to be mapped to their cofaas modules using M options.
+func getProtoIdent(ident string, file *protogen.File, g *protogen.GeneratedFile) string {
+	return g.QualifiedGoIdent(file.GoImportPath.Ident(ident))
+}
+
+// getServerImplementation returns the server implementation of method
+// registered with the generated grpc code of file
+func getServerImplementation(method *protogen.Method, file *protogen.File, g *protogen.GeneratedFile) string {
+	return getProtoIdent(method.Parent.GoName+"ServerImplementation."+method.GoName, file, g)
+}
+
+// serviceFile returns the file defining svc
+func serviceFile(gen *protogen.Plugin, svc *protogen.Service) *protogen.File {
+	return gen.FilesByPath[svc.Desc.ParentFile().Path()]
+}
+
+func genInitFunc(gen *protogen.Plugin, exportSvcs []*protogen.Service, importSvcs []*protogen.Service, g *protogen.GeneratedFile) {
+	g.P("func init() {")
+	for _, svc := range exportSvcs {
+		g.P(getInterfaceIdent("SetExports"+witnames.InterfaceGoName(witnames.Ident(svc.GoName)), g) + "(" + genExportStructName(svc) + "{})")
+	}
+	if len(importSvcs) > 0 {
+		g.P()
+	}
+	for _, svc := range importSvcs {
+		g.P(getProtoIdent("Set"+svc.GoName+"ClientImplementation", serviceFile(gen, svc), g) + "(" + genImportStructName(svc) + "{})")
+	}
+	g.P("}")
+}
+
+// genInitComponent generates the init-component functions of the
+// exported interfaces. The component is only initialized once even
+// if more than one service is exported.
+func genInitComponent(exportSvcs []*protogen.Service, importSvcs []*protogen.Service, g *protogen.GeneratedFile) {
+	g.P("var initOnce " + g.QualifiedGoIdent(syncPackage.Ident("Once")))
+	g.P()
+	g.P("func initComponent() {")
+	g.P("initOnce.Do(func() {")
+	g.P(g.QualifiedGoIdent(implPackage.Ident("Main")) + "()")
+	for _, svc := range importSvcs {
+		g.P(getWitIdent(svc, "InitComponent", g) + "()")
+	}
+	g.P("})")
+	g.P("}")
+	for _, svc := range exportSvcs {
+		g.P()
+		g.P("func (" + genExportStructName(svc) + ") InitComponent() {")
+		g.P("initComponent()")
+		g.P("}")
+	}
+}
+
+func genExportHandlers(gen *protogen.Plugin, svc *protogen.Service, conv *witConverter, g *protogen.GeneratedFile) {
+	for _, m := range svc.Methods {
+		if !checkMethod(gen, m) {
+			continue
+		}
+		if m.Desc.IsStreamingClient() {
+			genExportResourceMethod(m, conv, g)
+		} else if m.Desc.IsStreamingServer() {
+			genExportStreamMethod(m, conv, g)
+		} else {
+			genExportMethod(m, conv, g)
+		}
+	}
+}
+
+func genExportMethod(method *protogen.Method, conv *witConverter, g *protogen.GeneratedFile) {
+	retType := getInterfaceIdent("Result", g) + "[" + conv.messageWitType(method.Output, g) + ", int32]"
+
+	// Messages without fields are omitted from the WIT signature
+	var argDecl string
+	if !witnames.IsUnit(method.Input) {
+		argDecl = "arg " + conv.messageWitType(method.Input, g)
+	}
+	g.P("func (" + genExportStructName(method.Parent) + ") " +
+		getWitMethodName(method) +
+		" (" + argDecl + ") " + retType + "{")
+	if witnames.IsUnit(method.Input) {
+		g.P("param := &" + conv.protoType(method.Input.GoIdent, g) + "{}")
+	} else {
+		g.P("param := " + conv.call(method.Input, "arg", fromWit))
+	}
+	resVar, val := "res", conv.call(method.Output, "res", toWit)
+	if witnames.IsUnit(method.Output) {
+		resVar, val = "_", "struct{}{}"
+	}
+	g.P(resVar + ", err := " + getServerImplementation(method, conv.file, g) + "(" + g.QualifiedGoIdent(contextPackage.Ident("TODO")) + "(), param)")
+	g.P("if err != nil {")
+	g.P("return " + retType + "{Kind: " + getInterfaceIdent("Err", g) + ", Err: 1}")
+	g.P("}")
+	g.P()
+	g.P("return " + retType + "{Kind: " + getInterfaceIdent("Ok", g) + ", Err: 0, Val: " + val + "}")
+	g.P("}")
+}
+
+func genImportHandlers(gen *protogen.Plugin, svc *protogen.Service, conv *witConverter, g *protogen.GeneratedFile) {
+	for _, m := range svc.Methods {
+		if !checkMethod(gen, m) {
+			continue
+		}
+		if m.Desc.IsStreamingClient() {
+			genImportResourceMethod(m, conv, g)
+		} else if m.Desc.IsStreamingServer() {
+			genImportStreamMethod(m, conv, g)
+		} else {
+			genImportMethod(m, conv, g)
+		}
+	}
+}
+
+// checkMethod reports an error if method cannot be represented in WIT
+func checkMethod(gen *protogen.Plugin, method *protogen.Method) bool {
+	if method.Desc.IsStreamingClient() && witnames.IsUnit(method.Input) {
+		gen.Error(fmt.Errorf("streaming method %s cannot stream %s which has no fields",
+			method.Desc.FullName(), method.Input.Desc.FullName()))
+		return false
+	}
+	if method.Desc.IsStreamingServer() && witnames.IsUnit(method.Output) {
+		gen.Error(fmt.Errorf("streaming method %s cannot stream %s which has no fields",
+			method.Desc.FullName(), method.Output.Desc.FullName()))
+		return false
+	}
+	return true
+}
+
+func genImportMethod(method *protogen.Method, conv *witConverter, g *protogen.GeneratedFile) {
+	g.P("func (" + genImportStructName(method.Parent) + ") " + method.GoName + "(ctx " + g.QualifiedGoIdent(contextPackage.Ident("Context")) + ", in *" + conv.protoType(method.Input.GoIdent, g) + ", opts ...interface{}) (*" + conv.protoType(method.Output.GoIdent, g) + ", error) {")
+	// Messages without fields are omitted from the WIT signature
+	if witnames.IsUnit(method.Input) {
+		g.P("res := " + getWitIdent(method.Parent, method.GoName, g) + "()")
+	} else {
+		g.P("param := " + conv.call(method.Input, "in", toWit))
+		g.P("res := " + getWitIdent(method.Parent, method.GoName, g) + "(param)")
+	}
+	g.P("if res.IsErr() {")
+	g.P("return nil, " + g.QualifiedGoIdent(fmtPackage.Ident("Errorf")) + `("Call ` + method.GoName + ` failed with code: %d", res.Err)`)
+	g.P("}")
+	if witnames.IsUnit(method.Output) {
+		g.P("return &" + conv.protoType(method.Output.GoIdent, g) + "{}, nil")
+	} else {
+		g.P("return " + conv.call(method.Output, "res.Unwrap()", fromWit) + ", nil")
+	}
+	g.P("}")
+}
+
+func genLeadingComments(g *protogen.GeneratedFile, loc protoreflect.SourceLocation) {
+	for _, s := range loc.LeadingDetachedComments {
+		g.P(protogen.Comments(s))
+		g.P()
+	}
+	if s := loc.LeadingComments; s != "" {
+		g.P(protogen.Comments(s))
+		g.P()
+	}
+}
+
+func protocVersion(gen *protogen.Plugin) string {
+	v := gen.Request.GetCompilerVersion()
+	if v == nil {
+		return "(unknown)"
+	}
+	var suffix string
+	if s := v.GetSuffix(); s != "" {
+		suffix = "-" + s
+	}
+	return fmt.Sprintf("v%d.%d.%d%s", v.GetMajor(), v.GetMinor(), v.GetPatch(), suffix)
+}
@@ -0,0 +1,464 @@
+package gencomponent
+
+import (
+	"strings"
+
+	"github.com/truls/cofaas-go/protogen/types/internal_gengo/genid"
+	"github.com/truls/cofaas-go/protogen/witnames"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// direction of a conversion between protobuf and wit-bindgen types
+type direction int
+
+const (
+	toWit direction = iota
+	fromWit
+)
+
+func (d direction) String() string {
+	if d == toWit {
+		return "ToWit"
+	}
+	return "FromWit"
+}
+
+// witConverter generates functions converting the messages used by
+// the service of a file to and from the corresponding wit-bindgen
+// types
+type witConverter struct {
+	file     *protogen.File
+	service  *protogen.Service
+	names    *witnames.Names
+	messages []*protogen.Message
+	enums    []*protogen.Enum
+	seen     map[*protogen.Message]bool
+	enumSeen map[*protogen.Enum]bool
+}
+
+func newWitConverter(file *protogen.File, service *protogen.Service) *witConverter {
+	c := &witConverter{
+		file:     file,
+		service:  service,
+		names:    witnames.NewNames(service),
+		seen:     make(map[*protogen.Message]bool),
+		enumSeen: make(map[*protogen.Enum]bool),
+	}
+	for _, m := range service.Methods {
+		c.addMessage(m.Input)
+		c.addMessage(m.Output)
+	}
+	return c
+}
+
+// addMessage registers msg and all messages reachable from its fields
+// as requiring conversion functions
+func (c *witConverter) addMessage(msg *protogen.Message) {
+	if c.seen[msg] || witnames.IsUnit(msg) {
+		return
+	}
+	c.seen[msg] = true
+	c.messages = append(c.messages, msg)
+	if _, ok := witnames.KnownType(msg); ok {
+		return
+	}
+	for _, f := range msg.Fields {
+		if f.Desc.IsMap() {
+			// Only the value of a map entry can refer to other types
+			f = f.Message.Fields[1]
+		}
+		if f.Message != nil {
+			c.addMessage(f.Message)
+		}
+		if f.Enum != nil && !c.enumSeen[f.Enum] {
+			c.enumSeen[f.Enum] = true
+			c.enums = append(c.enums, f.Enum)
+		}
+	}
+}
+
+// funcName returns the name of the function converting the message
+// or enum ident in direction dir
+func (c *witConverter) funcName(ident protogen.GoIdent, dir direction) string {
+	return unexport(witnames.GoName(witnames.Ident(c.service.GoName))) + c.names.GoName(ident) + dir.String()
+}
+
+// call returns an expression converting expr of type msg in
+// direction dir
+func (c *witConverter) call(msg *protogen.Message, expr string, dir direction) string {
+	return c.funcName(msg.GoIdent, dir) + "(" + expr + ")"
+}
+
+// witType returns the wit-bindgen type of the message, enum or oneof
+// ident
+func (c *witConverter) witType(ident protogen.GoIdent, g *protogen.GeneratedFile) string {
+	return getWitIdent(c.service, c.names.Name(ident), g)
+}
+
+// messageWitType returns the Go type used by wit-bindgen for msg
+func (c *witConverter) messageWitType(msg *protogen.Message, g *protogen.GeneratedFile) string {
+	if witnames.IsUnit(msg) {
+		return "struct{}"
+	}
+	if _, ok := witnames.KnownType(msg); ok {
+		if isWrapper(msg) {
+			return scalarGoType(msg.Fields[0].Desc.Kind())
+		}
+		return "[]" + c.valueNodeType(g)
+	}
+	return c.witType(msg.GoIdent, g)
+}
+
+// protoType returns the protobuf type of the message or enum ident
+func (c *witConverter) protoType(ident protogen.GoIdent, g *protogen.GeneratedFile) string {
+	return g.QualifiedGoIdent(ident)
+}
+
+// genFunctions generates conversion functions in both directions for
+// all registered messages and enums
+func (c *witConverter) genFunctions(g *protogen.GeneratedFile) {
+	var valueTree *protogen.Message
+	for _, msg := range c.messages {
+		if _, ok := witnames.KnownType(msg); ok {
+			if witnames.IsValueTree(msg) {
+				valueTree = msg
+			}
+			c.genKnownToWit(msg, g)
+			c.genKnownFromWit(msg, g)
+			continue
+		}
+		c.genToWit(msg, g)
+		c.genFromWit(msg, g)
+	}
+	for _, enum := range c.enums {
+		c.genEnumToWit(enum, g)
+		c.genEnumFromWit(enum, g)
+	}
+	if valueTree != nil {
+		c.genValueNodeFunctions(valueTree, g)
+	}
+}
+
+// genEnumToWit generates a function converting a protobuf enum to a
+// wit-bindgen enum. Since protobuf enums are open, values without a
+// corresponding WIT case are mapped to the default value of the enum.
+func (c *witConverter) genEnumToWit(enum *protogen.Enum, g *protogen.GeneratedFile) {
+	witType := c.witType(enum.GoIdent, g)
+	values := witnames.EnumValues(enum)
+	g.P("func " + c.funcName(enum.GoIdent, toWit) + "(x " + c.protoType(enum.GoIdent, g) + ") " + witType + " {")
+	g.P("switch x {")
+	for _, v := range values[1:] {
+		g.P("case " + c.protoType(v.GoIdent, g) + ":")
+		g.P("return " + witType + witnames.GoName(witnames.EnumCase(v)) + "()")
+	}
+	g.P("}")
+	g.P("return " + witType + witnames.GoName(witnames.EnumCase(values[0])) + "()")
+	g.P("}")
+	g.P()
+}
+
+func (c *witConverter) genEnumFromWit(enum *protogen.Enum, g *protogen.GeneratedFile) {
+	witType := c.witType(enum.GoIdent, g)
+	values := witnames.EnumValues(enum)
+	g.P("func " + c.funcName(enum.GoIdent, fromWit) + "(x " + witType + ") " + c.protoType(enum.GoIdent, g) + " {")
+	g.P("switch x.Kind() {")
+	for _, v := range values[1:] {
+		g.P("case " + witType + "Kind" + witnames.GoName(witnames.EnumCase(v)) + ":")
+		g.P("return " + c.protoType(v.GoIdent, g))
+	}
+	g.P("}")
+	g.P("return " + c.protoType(values[0].GoIdent, g))
+	g.P("}")
+	g.P()
+}
+
+// genKnownToWit generates a function converting the well-known type
+// msg to the WIT type given by witnames.KnownType
+func (c *witConverter) genKnownToWit(msg *protogen.Message, g *protogen.GeneratedFile) {
+	witType := c.messageWitType(msg, g)
+	g.P("func " + c.funcName(msg.GoIdent, toWit) + "(x *" + c.protoType(msg.GoIdent, g) + ") " + witType + " {")
+	if isWrapper(msg) {
+		g.P("return x.GetValue()")
+	} else {
+		c.genValueTreeToWit(msg, g)
+	}
+	g.P("}")
+	g.P()
+}
+
+func (c *witConverter) genKnownFromWit(msg *protogen.Message, g *protogen.GeneratedFile) {
+	protoType := c.protoType(msg.GoIdent, g)
+	g.P("func " + c.funcName(msg.GoIdent, fromWit) + "(x " + c.messageWitType(msg, g) + ") *" + protoType + " {")
+	if isWrapper(msg) {
+		g.P("return &" + protoType + "{Value: x}")
+	} else {
+		c.genValueTreeFromWit(msg, g)
+	}
+	g.P("}")
+	g.P()
+}
+
+func (c *witConverter) genToWit(msg *protogen.Message, g *protogen.GeneratedFile) {
+	witType := c.witType(msg.GoIdent, g)
+	g.P("func " + c.funcName(msg.GoIdent, toWit) + "(x *" + c.protoType(msg.GoIdent, g) + ") " + witType + " {")
+	g.P("res := " + witType + "{}")
+	g.P("if x == nil {")
+	g.P("return res")
+	g.P("}")
+	for _, f := range msg.Fields {
+		if oneof := realOneof(f); oneof != nil {
+			if oneof.Fields[0] == f {
+				c.genOneofToWit(oneof, g)
+			}
+			continue
+		}
+		c.genFieldConversion(f, "res."+witFieldName(f), "x."+f.GoName, toWit, g)
+	}
+	g.P("return res")
+	g.P("}")
+	g.P()
+}
+
+func (c *witConverter) genFromWit(msg *protogen.Message, g *protogen.GeneratedFile) {
+	protoType := c.protoType(msg.GoIdent, g)
+	g.P("func " + c.funcName(msg.GoIdent, fromWit) + "(x " + c.witType(msg.GoIdent, g) + ") *" + protoType + " {")
+	g.P("res := &" + protoType + "{}")
+	for _, f := range msg.Fields {
+		if oneof := realOneof(f); oneof != nil {
+			if oneof.Fields[0] == f {
+				c.genOneofFromWit(oneof, g)
+			}
+			continue
+		}
+		c.genFieldConversion(f, "res."+f.GoName, "x."+witFieldName(f), fromWit, g)
+	}
+	g.P("return res")
+	g.P("}")
+	g.P()
+}
+
+// genOneofToWit generates statements setting the WIT variant
+// representing oneof to the case of the field that is set, if any
+func (c *witConverter) genOneofToWit(oneof *protogen.Oneof, g *protogen.GeneratedFile) {
+	witType := c.witType(oneof.GoIdent, g)
+	g.P("switch v := x." + oneof.GoName + ".(type) {")
+	for _, f := range oneof.Fields {
+		g.P("case *" + c.protoType(f.GoIdent, g) + ":")
+		g.P("res." + witOneofName(oneof) + ".Set(" + witType + witFieldName(f) + "(" + c.convertValue(f, "v."+f.GoName, toWit) + "))")
+	}
+	g.P("}")
+}
+
+// genOneofFromWit generates statements setting oneof to the wrapper
+// type of the case held by the WIT variant, if any
+func (c *witConverter) genOneofFromWit(oneof *protogen.Oneof, g *protogen.GeneratedFile) {
+	witType := c.witType(oneof.GoIdent, g)
+	g.P("if x." + witOneofName(oneof) + ".IsSome() {")
+	g.P("switch v := x." + witOneofName(oneof) + ".Unwrap(); v.Kind() {")
+	for _, f := range oneof.Fields {
+		g.P("case " + witType + "Kind" + witFieldName(f) + ":")
+		g.P("res." + oneof.GoName + " = &" + c.protoType(f.GoIdent, g) + "{" + f.GoName + ": " + c.convertValue(f, "v.Get"+witFieldName(f)+"()", fromWit) + "}")
+	}
+	g.P("}")
+	g.P("}")
+}
+
+// genFieldConversion generates statements assigning the value of src
+// converted in direction dir to dst
+func (c *witConverter) genFieldConversion(field *protogen.Field, dst string, src string, dir direction, g *protogen.GeneratedFile) {
+	if !needsConversion(field) {
+		g.P(dst + " = " + src)
+		return
+	}
+	if witnames.IsOptional(field) {
+		c.genOptionalConversion(field, dst, src, dir, g)
+		return
+	}
+	if field.Desc.IsMap() {
+		c.genMapConversion(field, dst, src, dir, g)
+		return
+	}
+	if field.Desc.IsList() {
+		g.P(dst + " = make([]" + c.elemType(field, dir, g) + ", len(" + src + "))")
+		g.P("for i, v := range " + src + " {")
+		g.P(dst + "[i] = " + c.convertValue(field, "v", dir))
+		g.P("}")
+		return
+	}
+	g.P(dst + " = " + c.convertValue(field, src, dir))
+}
+
+// genOptionalConversion generates statements converting the field
+// src with explicit presence to or from a wit-bindgen option. Unset
+// fields are represented by nil, either as a nil pointer or, for bytes
+// fields, as a nil slice.
+func (c *witConverter) genOptionalConversion(field *protogen.Field, dst string, src string, dir direction, g *protogen.GeneratedFile) {
+	isPointer := field.Message == nil && field.Desc.Kind() != protoreflect.BytesKind
+	if dir == toWit {
+		g.P("if " + src + " != nil {")
+		if isPointer {
+			g.P(dst + ".Set(" + c.convertValue(field, "*"+src, dir) + ")")
+		} else {
+			g.P(dst + ".Set(" + c.convertValue(field, src, dir) + ")")
+		}
+		g.P("}")
+		return
+	}
+	g.P("if " + src + ".IsSome() {")
+	switch {
+	case isPointer:
+		g.P("v := " + c.convertValue(field, src+".Unwrap()", dir))
+		g.P(dst + " = &v")
+	case field.Message == nil:
+		// Copy to a non-nil slice since an empty list may be nil
+		g.P(dst + " = append([]byte{}, " + src + ".Unwrap()...)")
+	default:
+		g.P(dst + " = " + c.convertValue(field, src+".Unwrap()", dir))
+	}
+	g.P("}")
+}
+
+// genMapConversion generates statements converting the map src to or
+// from the list of key-value tuples used by wit-bindgen. Since the
+// iteration order of Go maps is random, the tuples are sorted by key
+// to make the WIT representation deterministic.
+func (c *witConverter) genMapConversion(field *protogen.Field, dst string, src string, dir direction, g *protogen.GeneratedFile) {
+	key, value := field.Message.Fields[0], field.Message.Fields[1]
+	if dir == fromWit {
+		keyType := scalarGoType(key.Desc.Kind())
+		var valueType string
+		switch {
+		case value.Message != nil:
+			valueType = "*" + c.protoType(value.Message.GoIdent, g)
+		case value.Enum != nil:
+			valueType = c.protoType(value.Enum.GoIdent, g)
+		default:
+			valueType = scalarGoType(value.Desc.Kind())
+		}
+		g.P(dst + " = make(map[" + keyType + "]" + valueType + ", len(" + src + "))")
+		g.P("for _, v := range " + src + " {")
+		g.P(dst + "[v.F0] = " + c.convertValue(value, "v.F1", dir))
+		g.P("}")
+		return
+	}
+	tupleType := c.tupleType(field, g)
+	g.P(dst + " = make([]" + tupleType + ", 0, len(" + src + "))")
+	g.P("for k, v := range " + src + " {")
+	g.P(dst + " = append(" + dst + ", " + tupleType + "{F0: k, F1: " + c.convertValue(value, "v", dir) + "})")
+	g.P("}")
+	g.P(g.QualifiedGoIdent(sortPackage.Ident("Slice")) + "(" + dst + ", func(i, j int) bool {")
+	a, b := dst+"[i].F0", dst+"[j].F0"
+	if key.Desc.Kind() == protoreflect.BoolKind {
+		g.P("return !" + a + " && " + b)
+	} else {
+		g.P("return " + a + " < " + b)
+	}
+	g.P("})")
+}
+
+// tupleType returns the wit-bindgen tuple type representing an entry
+// of the map field
+func (c *witConverter) tupleType(field *protogen.Field, g *protogen.GeneratedFile) string {
+	iface := witnames.Ident(c.service.GoName)
+	key, value := field.Message.Fields[0], field.Message.Fields[1]
+	return getInterfaceIdent(witnames.TupleGoName(iface, c.witValueType(key), c.witValueType(value)), g)
+}
+
+// convertValue returns an expression converting the singular value
+// expr of field in direction dir
+func (c *witConverter) convertValue(field *protogen.Field, expr string, dir direction) string {
+	switch {
+	case field.Message != nil:
+		return c.call(field.Message, expr, dir)
+	case field.Enum != nil:
+		return c.funcName(field.Enum.GoIdent, dir) + "(" + expr + ")"
+	}
+	return expr
+}
+
+// elemType returns the Go type of the converted elements of the list
+// field
+func (c *witConverter) elemType(field *protogen.Field, dir direction, g *protogen.GeneratedFile) string {
+	switch {
+	case field.Message != nil && dir == toWit:
+		return c.messageWitType(field.Message, g)
+	case field.Message != nil:
+		return "*" + c.protoType(field.Message.GoIdent, g)
+	case dir == toWit:
+		return c.witType(field.Enum.GoIdent, g)
+	default:
+		return c.protoType(field.Enum.GoIdent, g)
+	}
+}
+
+// needsConversion reports whether a value of field can be assigned
+// directly between the protobuf and wit-bindgen types
+func needsConversion(field *protogen.Field) bool {
+	return witnames.IsOptional(field) || field.Desc.IsMap() || field.Message != nil || field.Enum != nil
+}
+
+// isWrapper reports whether msg is one of the well-known wrapper types
+func isWrapper(msg *protogen.Message) bool {
+	return msg.Desc.ParentFile().Path() == genid.File_google_protobuf_wrappers_proto
+}
+
+// witValueType returns the WIT type of a singular value of field
+func (c *witConverter) witValueType(field *protogen.Field) string {
+	switch {
+	case field.Message != nil:
+		return c.names.MessageType(field.Message)
+	case field.Enum != nil:
+		return witnames.Ident(c.names.Name(field.Enum.GoIdent))
+	}
+	// Unsupported kinds are rejected when generating the WIT file
+	typ, _ := witnames.ScalarType(field.Desc.Kind())
+	return typ
+}
+
+// witFieldName returns the name of the field generated by wit-bindgen
+// for field
+func witFieldName(field *protogen.Field) string {
+	return witnames.GoName(witnames.Ident(string(field.Desc.Name())))
+}
+
+// witOneofName returns the name of the field generated by wit-bindgen
+// for oneof
+func witOneofName(oneof *protogen.Oneof) string {
+	return witnames.GoName(witnames.Ident(string(oneof.Desc.Name())))
+}
+
+// realOneof returns the oneof containing field unless it is the
+// synthetic oneof of a proto3 optional field
+func realOneof(field *protogen.Field) *protogen.Oneof {
+	if field.Oneof == nil || field.Oneof.Desc.IsSynthetic() {
+		return nil
+	}
+	return field.Oneof
+}
+
+// scalarGoType returns the Go type of values of the scalar protobuf
+// kind
+func scalarGoType(kind protoreflect.Kind) string {
+	switch kind {
+	case protoreflect.BoolKind:
+		return "bool"
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return "int32"
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return "uint32"
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return "int64"
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return "uint64"
+	case protoreflect.FloatKind:
+		return "float32"
+	case protoreflect.DoubleKind:
+		return "float64"
+	case protoreflect.BytesKind:
+		return "[]byte"
+	}
+	return "string"
+}
+
+func unexport(s string) string { return strings.ToLower(s[:1]) + s[1:] }
@@ -0,0 +1,356 @@
+package gencomponent
+
+import (
+	"github.com/truls/cofaas-go/protogen/witnames"
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+// Server-streaming methods are represented in WIT by a function
+// returning the list of streamed messages. The messages sent by the
+// server implementation are buffered in the exporting component and
+// replayed by the stream returned to the client in the importing
+// component.
+
+// streamTypeName returns the name of the glue type implementing the
+// stream interface with suffix side for method
+func (c *witConverter) streamTypeName(method *protogen.Method, side string) string {
+	return unexport(witnames.GoName(witnames.Ident(c.service.GoName))) + method.GoName + side + "Stream"
+}
+
+// streamInterface returns the stream interface generated by the grpc
+// plugin for the side of method
+func (c *witConverter) streamInterface(method *protogen.Method, side string, g *protogen.GeneratedFile) string {
+	return getProtoIdent(method.Parent.GoName+"_"+method.GoName+side, c.file, g)
+}
+
+func genExportStreamMethod(method *protogen.Method, conv *witConverter, g *protogen.GeneratedFile) {
+	retType := getInterfaceIdent("Result", g) + "[[]" + conv.messageWitType(method.Output, g) + ", int32]"
+	streamType := conv.streamTypeName(method, "Server")
+
+	// Messages without fields are omitted from the WIT signature
+	var argDecl string
+	if !witnames.IsUnit(method.Input) {
+		argDecl = "arg " + conv.messageWitType(method.Input, g)
+	}
+	g.P("func (" + genExportStructName(method.Parent) + ") " +
+		getWitMethodName(method) +
+		" (" + argDecl + ") " + retType + "{")
+	if witnames.IsUnit(method.Input) {
+		g.P("param := &" + conv.protoType(method.Input.GoIdent, g) + "{}")
+	} else {
+		g.P("param := " + conv.call(method.Input, "arg", fromWit))
+	}
+	g.P("stream := &" + streamType + "{ctx: " + g.QualifiedGoIdent(contextPackage.Ident("TODO")) + "()}")
+	g.P("err := " + getServerImplementation(method, conv.file, g) + "(param, stream)")
+	g.P("if err != nil {")
+	g.P("return " + retType + "{Kind: " + getInterfaceIdent("Err", g) + ", Err: 1}")
+	g.P("}")
+	g.P()
+	g.P("return " + retType + "{Kind: " + getInterfaceIdent("Ok", g) + ", Err: 0, Val: stream.msgs}")
+	g.P("}")
+	g.P()
+
+	// Messages are converted when sent since the server is free
+	// to modify them once Send returns
+	g.P("// ", streamType, " buffers the messages sent by ", method.GoName)
+	g.P("type " + streamType + " struct {")
+	g.P("ctx " + g.QualifiedGoIdent(contextPackage.Ident("Context")))
+	g.P("msgs []" + conv.messageWitType(method.Output, g))
+	g.P("}")
+	g.P()
+	g.P("func (s *" + streamType + ") Send(m *" + conv.protoType(method.Output.GoIdent, g) + ") error {")
+	g.P("s.msgs = append(s.msgs, " + conv.call(method.Output, "m", toWit) + ")")
+	g.P("return nil")
+	g.P("}")
+	g.P()
+	g.P("func (s *" + streamType + ") Context() " + g.QualifiedGoIdent(contextPackage.Ident("Context")) + " {")
+	g.P("return s.ctx")
+	g.P("}")
+	g.P()
+}
+
+func genImportStreamMethod(method *protogen.Method, conv *witConverter, g *protogen.GeneratedFile) {
+	streamType := conv.streamTypeName(method, "Client")
+
+	g.P("func (" + genImportStructName(method.Parent) + ") " + method.GoName + "(ctx " + g.QualifiedGoIdent(contextPackage.Ident("Context")) + ", in *" + conv.protoType(method.Input.GoIdent, g) + ", opts ...interface{}) (" + conv.streamInterface(method, "Client", g) + ", error) {")
+	// Messages without fields are omitted from the WIT signature
+	if witnames.IsUnit(method.Input) {
+		g.P("res := " + getWitIdent(method.Parent, method.GoName, g) + "()")
+	} else {
+		g.P("param := " + conv.call(method.Input, "in", toWit))
+		g.P("res := " + getWitIdent(method.Parent, method.GoName, g) + "(param)")
+	}
+	g.P("if res.IsErr() {")
+	g.P("return nil, " + g.QualifiedGoIdent(fmtPackage.Ident("Errorf")) + `("Call ` + method.GoName + ` failed with code: %d", res.Err)`)
+	g.P("}")
+	g.P("return &" + streamType + "{ctx: ctx, msgs: res.Unwrap()}, nil")
+	g.P("}")
+	g.P()
+
+	g.P("// ", streamType, " replays the messages returned by ", method.GoName)
+	g.P("type " + streamType + " struct {")
+	g.P("ctx " + g.QualifiedGoIdent(contextPackage.Ident("Context")))
+	g.P("msgs []" + conv.messageWitType(method.Output, g))
+	g.P("}")
+	g.P()
+	g.P("func (s *" + streamType + ") Recv() (*" + conv.protoType(method.Output.GoIdent, g) + ", error) {")
+	g.P("if len(s.msgs) == 0 {")
+	g.P("return nil, " + g.QualifiedGoIdent(ioPackage.Ident("EOF")))
+	g.P("}")
+	g.P("m := s.msgs[0]")
+	g.P("s.msgs = s.msgs[1:]")
+	g.P("return " + conv.call(method.Output, "m", fromWit) + ", nil")
+	g.P("}")
+	g.P()
+	// The request is sent in full by the call
+	g.P("func (s *" + streamType + ") CloseSend() error {")
+	g.P("return nil")
+	g.P("}")
+	g.P()
+	g.P("func (s *" + streamType + ") Context() " + g.QualifiedGoIdent(contextPackage.Ident("Context")) + " {")
+	g.P("return s.ctx")
+	g.P("}")
+	g.P()
+}
+
+// Client-streaming and bidirectional streaming methods are represented
+// in WIT by a resource exported by the server. A call of the method
+// creates an instance of the resource to which the client sends its
+// messages. The server implementation is invoked with the buffered
+// messages once the client closes its side of the stream. A
+// bidirectional stream is therefore not interactive. The first Recv of
+// the client closes its side of the stream and later sends fail with
+// io.EOF.
+
+// streamResource returns the WIT resource of the streaming method
+func streamResource(method *protogen.Method) string {
+	return witnames.StreamResource(method.GoName)
+}
+
+// unitResult returns a successful result without a value
+func unitResult(g *protogen.GeneratedFile) string {
+	return getInterfaceIdent("Result", g) + "[struct{}, int32]{Kind: " + getInterfaceIdent("Ok", g) + ", Err: 0, Val: struct{}{}}"
+}
+
+func genExportResourceMethod(method *protogen.Method, conv *witConverter, g *protogen.GeneratedFile) {
+	res := streamResource(method)
+	streamType := conv.streamTypeName(method, "Server")
+	ctxType := g.QualifiedGoIdent(contextPackage.Ident("Context"))
+	bidi := method.Desc.IsStreamingServer()
+
+	g.P("func (" + genExportStructName(method.Parent) + ") " + witnames.ResourceConstructorGoName(res) + "() " +
+		getInterfaceIdent(witnames.ResourceExportGoName(witnames.Ident(conv.service.GoName), res), g) + " {")
+	g.P("return &" + streamType + "{ctx: " + g.QualifiedGoIdent(contextPackage.Ident("TODO")) + "()}")
+	g.P("}")
+	g.P()
+
+	g.P("// ", streamType, " buffers the messages sent by the client of ", method.GoName)
+	g.P("type " + streamType + " struct {")
+	g.P("ctx " + ctxType)
+	g.P("msgs []*" + conv.protoType(method.Input.GoIdent, g))
+	if bidi {
+		g.P("out []" + conv.messageWitType(method.Output, g))
+		g.P("done bool")
+		g.P("err error")
+	} else if !witnames.IsUnit(method.Output) {
+		g.P("res " + conv.messageWitType(method.Output, g))
+	}
+	g.P("}")
+	g.P()
+
+	// WIT resource methods
+	g.P("func (s *" + streamType + ") " + witnames.ResourceMethodGoName(res, "send") + "(msg " + conv.messageWitType(method.Input, g) + ") " + getInterfaceIdent("Result", g) + "[struct{}, int32] {")
+	if bidi {
+		// The server has already been invoked with the messages
+		// sent before
+		g.P("if s.done {")
+		g.P("return " + getInterfaceIdent("Result", g) + "[struct{}, int32]{Kind: " + getInterfaceIdent("Err", g) + ", Err: 1}")
+		g.P("}")
+	}
+	g.P("s.msgs = append(s.msgs, " + conv.call(method.Input, "msg", fromWit) + ")")
+	g.P("return " + unitResult(g))
+	g.P("}")
+	g.P()
+	if bidi {
+		optType := getInterfaceIdent("Option", g) + "[" + conv.messageWitType(method.Output, g) + "]"
+		retType := getInterfaceIdent("Result", g) + "[" + optType + ", int32]"
+		g.P("func (s *" + streamType + ") " + witnames.ResourceMethodGoName(res, "close-send") + "() " + getInterfaceIdent("Result", g) + "[struct{}, int32] {")
+		g.P("s.run()")
+		g.P("return " + unitResult(g))
+		g.P("}")
+		g.P()
+		g.P("// " + witnames.ResourceMethodGoName(res, "recv") + " closes the sending side of the stream if the")
+		g.P("// client has not done so since the server is only invoked once all")
+		g.P("// messages have been received. As with gRPC, an error returned by the")
+		g.P("// server is reported after the messages it has sent.")
+		g.P("func (s *" + streamType + ") " + witnames.ResourceMethodGoName(res, "recv") + "() " + retType + " {")
+		g.P("s.run()")
+		g.P("var res " + optType)
+		g.P("if len(s.out) > 0 {")
+		g.P("res.Set(s.out[0])")
+		g.P("s.out = s.out[1:]")
+		g.P("} else if s.err != nil {")
+		g.P("return " + retType + "{Kind: " + getInterfaceIdent("Err", g) + ", Err: 1}")
+		g.P("}")
+		g.P("return " + retType + "{Kind: " + getInterfaceIdent("Ok", g) + ", Err: 0, Val: res}")
+		g.P("}")
+		g.P()
+		g.P("// run invokes the server implementation the first time it is called")
+		g.P("func (s *" + streamType + ") run() {")
+		g.P("if s.done {")
+		g.P("return")
+		g.P("}")
+		g.P("s.done = true")
+		g.P("s.err = " + getServerImplementation(method, conv.file, g) + "(s)")
+		g.P("}")
+		g.P()
+	} else {
+		retType := getInterfaceIdent("Result", g) + "[" + conv.messageWitType(method.Output, g) + ", int32]"
+		val := "s.res"
+		if witnames.IsUnit(method.Output) {
+			val = "struct{}{}"
+		}
+		g.P("func (s *" + streamType + ") " + witnames.ResourceMethodGoName(res, "close-and-recv") + "() " + retType + " {")
+		g.P("err := " + getServerImplementation(method, conv.file, g) + "(s)")
+		g.P("if err != nil {")
+		g.P("return " + retType + "{Kind: " + getInterfaceIdent("Err", g) + ", Err: 1}")
+		g.P("}")
+		g.P()
+		g.P("return " + retType + "{Kind: " + getInterfaceIdent("Ok", g) + ", Err: 0, Val: " + val + "}")
+		g.P("}")
+		g.P()
+	}
+
+	// Stream interface used by the server implementation
+	if bidi {
+		g.P("func (s *" + streamType + ") Send(m *" + conv.protoType(method.Output.GoIdent, g) + ") error {")
+		g.P("s.out = append(s.out, " + conv.call(method.Output, "m", toWit) + ")")
+		g.P("return nil")
+		g.P("}")
+	} else {
+		g.P("func (s *" + streamType + ") SendAndClose(m *" + conv.protoType(method.Output.GoIdent, g) + ") error {")
+		if !witnames.IsUnit(method.Output) {
+			g.P("s.res = " + conv.call(method.Output, "m", toWit))
+		}
+		g.P("return nil")
+		g.P("}")
+	}
+	g.P()
+	g.P("func (s *" + streamType + ") Recv() (*" + conv.protoType(method.Input.GoIdent, g) + ", error) {")
+	g.P("if len(s.msgs) == 0 {")
+	g.P("return nil, " + g.QualifiedGoIdent(ioPackage.Ident("EOF")))
+	g.P("}")
+	g.P("m := s.msgs[0]")
+	g.P("s.msgs = s.msgs[1:]")
+	g.P("return m, nil")
+	g.P("}")
+	g.P()
+	g.P("func (s *" + streamType + ") Context() " + ctxType + " {")
+	g.P("return s.ctx")
+	g.P("}")
+	g.P()
+}
+
+func genImportResourceMethod(method *protogen.Method, conv *witConverter, g *protogen.GeneratedFile) {
+	res := streamResource(method)
+	streamType := conv.streamTypeName(method, "Client")
+	ctxType := g.QualifiedGoIdent(contextPackage.Ident("Context"))
+	bidi := method.Desc.IsStreamingServer()
+	callErr := func() {
+		g.P("if res.IsErr() {")
+		g.P("return nil, " + g.QualifiedGoIdent(fmtPackage.Ident("Errorf")) + `("Call ` + method.GoName + ` failed with code: %d", res.Err)`)
+		g.P("}")
+	}
+
+	g.P("func (" + genImportStructName(method.Parent) + ") " + method.GoName + "(ctx " + ctxType + ", opts ...interface{}) (" + conv.streamInterface(method, "Client", g) + ", error) {")
+	g.P("return &" + streamType + "{ctx: ctx, stream: " + getInterfaceIdent(witnames.ResourceImportConstructorGoName(res), g) + "()}, nil")
+	g.P("}")
+	g.P()
+
+	g.P("// ", streamType, " sends messages to an instance of the ", res, " resource")
+	if bidi {
+		g.P("//")
+		g.P("// The server is invoked with the messages sent before the first Recv")
+		g.P("// which closes the sending side of the stream")
+	}
+	g.P("type " + streamType + " struct {")
+	g.P("ctx " + ctxType)
+	g.P("stream " + getWitIdent(method.Parent, res, g))
+	g.P("closed bool")
+	if bidi {
+		g.P("done bool")
+	}
+	g.P("}")
+	g.P()
+
+	// The resource must not be used once the sending side is closed
+	// since it may have been dropped
+	g.P("func (s *" + streamType + ") Send(m *" + conv.protoType(method.Input.GoIdent, g) + ") error {")
+	g.P("if s.closed {")
+	g.P("return " + g.QualifiedGoIdent(ioPackage.Ident("EOF")))
+	g.P("}")
+	g.P("res := s.stream.Send(" + conv.call(method.Input, "m", toWit) + ")")
+	g.P("if res.IsErr() {")
+	g.P("return " + g.QualifiedGoIdent(fmtPackage.Ident("Errorf")) + `("Call ` + method.GoName + ` failed with code: %d", res.Err)`)
+	g.P("}")
+	g.P("return nil")
+	g.P("}")
+	g.P()
+
+	if bidi {
+		g.P("func (s *" + streamType + ") CloseSend() error {")
+		g.P("if s.closed {")
+		g.P("return nil")
+		g.P("}")
+		g.P("s.closed = true")
+		g.P("res := s.stream.CloseSend()")
+		g.P("if res.IsErr() {")
+		g.P("return " + g.QualifiedGoIdent(fmtPackage.Ident("Errorf")) + `("Call ` + method.GoName + ` failed with code: %d", res.Err)`)
+		g.P("}")
+		g.P("return nil")
+		g.P("}")
+		g.P()
+		g.P("func (s *" + streamType + ") Recv() (*" + conv.protoType(method.Output.GoIdent, g) + ", error) {")
+		g.P("if s.done {")
+		g.P("return nil, " + g.QualifiedGoIdent(ioPackage.Ident("EOF")))
+		g.P("}")
+		// The server is invoked by the first recv of the resource
+		// which closes the sending side. The resource is dropped
+		// once the stream ends, either normally or with an error.
+		g.P("s.closed = true")
+		g.P("res := s.stream.Recv()")
+		g.P("if res.IsErr() {")
+		g.P("s.done = true")
+		g.P("s.stream.Drop()")
+		g.P("return nil, " + g.QualifiedGoIdent(fmtPackage.Ident("Errorf")) + `("Call ` + method.GoName + ` failed with code: %d", res.Err)`)
+		g.P("}")
+		g.P("msg := res.Unwrap()")
+		g.P("if msg.IsNone() {")
+		g.P("s.done = true")
+		g.P("s.stream.Drop()")
+		g.P("return nil, " + g.QualifiedGoIdent(ioPackage.Ident("EOF")))
+		g.P("}")
+		g.P("return " + conv.call(method.Output, "msg.Unwrap()", fromWit) + ", nil")
+		g.P("}")
+	} else {
+		g.P("func (s *" + streamType + ") CloseAndRecv() (*" + conv.protoType(method.Output.GoIdent, g) + ", error) {")
+		g.P("if s.closed {")
+		g.P("return nil, " + g.QualifiedGoIdent(ioPackage.Ident("EOF")))
+		g.P("}")
+		g.P("s.closed = true")
+		g.P("defer s.stream.Drop()")
+		g.P("res := s.stream.CloseAndRecv()")
+		callErr()
+		if witnames.IsUnit(method.Output) {
+			g.P("return &" + conv.protoType(method.Output.GoIdent, g) + "{}, nil")
+		} else {
+			g.P("return " + conv.call(method.Output, "res.Unwrap()", fromWit) + ", nil")
+		}
+		g.P("}")
+	}
+	g.P()
+	g.P("func (s *" + streamType + ") Context() " + ctxType + " {")
+	g.P("return s.ctx")
+	g.P("}")
+	g.P()
+}
@@ -0,0 +1,146 @@
+package gencomponent
+
+import (
+	"github.com/truls/cofaas-go/protogen/witnames"
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+// The types of struct.proto are recursive and are converted to and
+// from lists of witnames.ValueNode. A value is encoded by appending
+// its node followed by the nodes of its children. When decoding, a
+// child must follow its parent which rules out cycles. Since the lists
+// are only produced by the glue, an invalid list is a bug and the
+// conversion panics.
+
+// valueNodeType returns the wit-bindgen type of witnames.ValueNode
+func (c *witConverter) valueNodeType(g *protogen.GeneratedFile) string {
+	return getWitIdent(c.service, witnames.ValueNode, g)
+}
+
+// helperName returns the name of the conversion helper name of the
+// service
+func (c *witConverter) helperName(name string) string {
+	return unexport(witnames.GoName(witnames.Ident(c.service.GoName))) + name
+}
+
+// genValueTreeToWit generates the body of the function converting x of
+// the struct.proto type msg to a list of nodes
+func (c *witConverter) genValueTreeToWit(msg *protogen.Message, g *protogen.GeneratedFile) {
+	g.P("return " + c.helperName("Append"+msg.GoIdent.GoName+"Node") + "(nil, x)")
+}
+
+// genValueTreeFromWit generates the body of the function converting
+// the list of nodes x to the struct.proto type msg
+func (c *witConverter) genValueTreeFromWit(msg *protogen.Message, g *protogen.GeneratedFile) {
+	g.P("return " + c.helperName(msg.GoIdent.GoName+"FromNodes") + "(x, -1, 0)")
+}
+
+// genValueNodeFunctions generates the functions encoding and decoding
+// the types of struct.proto which are defined in the Go package of msg
+func (c *witConverter) genValueNodeFunctions(msg *protogen.Message, g *protogen.GeneratedFile) {
+	pb := func(name string) string {
+		return g.QualifiedGoIdent(msg.GoIdent.GoImportPath.Ident(name))
+	}
+	node := c.valueNodeType(g)
+	nodes := "[]" + node
+	tuple := getInterfaceIdent(witnames.TupleGoName(witnames.Ident(c.service.GoName), "string", "u32"), g)
+	appendValue := c.helperName("AppendValueNode")
+	appendStruct := c.helperName("AppendStructNode")
+	appendList := c.helperName("AppendListValueNode")
+	valueNode := c.helperName("ValueNode")
+	valueFrom := c.helperName("ValueFromNodes")
+	structFrom := c.helperName("StructFromNodes")
+	listFrom := c.helperName("ListValueFromNodes")
+
+	g.P("// ", appendValue, " appends the nodes encoding x to nodes")
+	g.P("func " + appendValue + "(nodes " + nodes + ", x *" + pb("Value") + ") " + nodes + " {")
+	g.P("switch k := x.GetKind().(type) {")
+	for _, kind := range []string{"NumberValue", "StringValue", "BoolValue"} {
+		g.P("case *" + pb("Value_"+kind) + ":")
+		g.P("return append(nodes, " + node + kind + "(k." + kind + "))")
+	}
+	g.P("case *" + pb("Value_StructValue") + ":")
+	g.P("return " + appendStruct + "(nodes, k.StructValue)")
+	g.P("case *" + pb("Value_ListValue") + ":")
+	g.P("return " + appendList + "(nodes, k.ListValue)")
+	g.P("}")
+	g.P("return append(nodes, " + node + "NullValue())")
+	g.P("}")
+	g.P()
+
+	// The fields are sorted by key to make the encoding deterministic
+	g.P("func " + appendStruct + "(nodes " + nodes + ", x *" + pb("Struct") + ") " + nodes + " {")
+	g.P("keys := make([]string, 0, len(x.GetFields()))")
+	g.P("for k := range x.GetFields() {")
+	g.P("keys = append(keys, k)")
+	g.P("}")
+	g.P(g.QualifiedGoIdent(sortPackage.Ident("Strings")) + "(keys)")
+	g.P("i := len(nodes)")
+	g.P("nodes = append(nodes, " + node + "{})")
+	g.P("fields := make([]" + tuple + ", len(keys))")
+	g.P("for j, k := range keys {")
+	g.P("fields[j] = " + tuple + "{F0: k, F1: uint32(len(nodes))}")
+	g.P("nodes = " + appendValue + "(nodes, x.GetFields()[k])")
+	g.P("}")
+	g.P("nodes[i] = " + node + "StructValue(fields)")
+	g.P("return nodes")
+	g.P("}")
+	g.P()
+
+	g.P("func " + appendList + "(nodes " + nodes + ", x *" + pb("ListValue") + ") " + nodes + " {")
+	g.P("i := len(nodes)")
+	g.P("nodes = append(nodes, " + node + "{})")
+	g.P("values := make([]uint32, len(x.GetValues()))")
+	g.P("for j, v := range x.GetValues() {")
+	g.P("values[j] = uint32(len(nodes))")
+	g.P("nodes = " + appendValue + "(nodes, v)")
+	g.P("}")
+	g.P("nodes[i] = " + node + "ListValue(values)")
+	g.P("return nodes")
+	g.P("}")
+	g.P()
+
+	g.P("// ", valueNode, " returns node i of nodes which is a child of node parent")
+	g.P("func " + valueNode + "(nodes " + nodes + ", parent int, i uint32) " + node + " {")
+	g.P("if int(i) <= parent || int(i) >= len(nodes) {")
+	g.P("panic(" + g.QualifiedGoIdent(fmtPackage.Ident("Sprintf")) + `("invalid reference to node %d of a google.protobuf.Value", i))`)
+	g.P("}")
+	g.P("return nodes[i]")
+	g.P("}")
+	g.P()
+
+	g.P("func " + valueFrom + "(nodes " + nodes + ", parent int, i uint32) *" + pb("Value") + " {")
+	g.P("switch n := " + valueNode + "(nodes, parent, i); n.Kind() {")
+	for _, kind := range []string{"NumberValue", "StringValue", "BoolValue"} {
+		g.P("case " + node + "Kind" + kind + ":")
+		g.P("return &" + pb("Value") + "{Kind: &" + pb("Value_"+kind) + "{" + kind + ": n.Get" + kind + "()}}")
+	}
+	g.P("case " + node + "KindStructValue:")
+	g.P("return &" + pb("Value") + "{Kind: &" + pb("Value_StructValue") + "{StructValue: " + structFrom + "(nodes, parent, i)}}")
+	g.P("case " + node + "KindListValue:")
+	g.P("return &" + pb("Value") + "{Kind: &" + pb("Value_ListValue") + "{ListValue: " + listFrom + "(nodes, parent, i)}}")
+	g.P("}")
+	g.P("return &" + pb("Value") + "{Kind: &" + pb("Value_NullValue") + "{}}")
+	g.P("}")
+	g.P()
+
+	g.P("func " + structFrom + "(nodes " + nodes + ", parent int, i uint32) *" + pb("Struct") + " {")
+	g.P("fields := " + valueNode + "(nodes, parent, i).GetStructValue()")
+	g.P("res := &" + pb("Struct") + "{Fields: make(map[string]*" + pb("Value") + ", len(fields))}")
+	g.P("for _, f := range fields {")
+	g.P("res.Fields[f.F0] = " + valueFrom + "(nodes, int(i), f.F1)")
+	g.P("}")
+	g.P("return res")
+	g.P("}")
+	g.P()
+
+	g.P("func " + listFrom + "(nodes " + nodes + ", parent int, i uint32) *" + pb("ListValue") + " {")
+	g.P("values := " + valueNode + "(nodes, parent, i).GetListValue()")
+	g.P("res := &" + pb("ListValue") + "{Values: make([]*" + pb("Value") + ", len(values))}")
+	g.P("for j, v := range values {")
+	g.P("res.Values[j] = " + valueFrom + "(nodes, int(i), v)")
+	g.P("}")
+	g.P("return res")
+	g.P("}")
+	g.P()
+}
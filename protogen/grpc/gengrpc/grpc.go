@@ -16,7 +16,7 @@
  *
  */
 
-package main
+package gengrpc
 
 import (
 	"fmt"
@@ -38,7 +38,7 @@ type serviceGenerateHelperInterface interface {
 	generateClientStruct(g *protogen.GeneratedFile, clientName string)
 	generateUnimplementedClientStruct(g *protogen.GeneratedFile, clientName string)
 	generateNewClientDefinitions(g *protogen.GeneratedFile, service *protogen.Service, clientName string)
-	generateUnimplementedServerType(gen *protogen.Plugin, file *protogen.File, g *protogen.GeneratedFile, service *protogen.Service)
+	generateUnimplementedServerType(gen *protogen.Plugin, file *protogen.File, g *protogen.GeneratedFile, service *protogen.Service, requireUnimplemented bool)
 	generateServerFunctions(gen *protogen.Plugin, file *protogen.File, g *protogen.GeneratedFile, service *protogen.Service, serverType string, serviceDescVar string)
 	formatHandlerFuncName(service *protogen.Service, hname string) string
 }
@@ -73,13 +73,13 @@ func (serviceGenerateHelper) generateClientStruct(g *protogen.GeneratedFile, cli
 }
 
 func (serviceGenerateHelper) generateNewClientDefinitions(g *protogen.GeneratedFile, service *protogen.Service, clientName string) {
-	g.P("return clientImplementation")
+	g.P("return ", unexport(clientName), "Implementation")
 }
 
-func (serviceGenerateHelper) generateUnimplementedServerType(gen *protogen.Plugin, file *protogen.File, g *protogen.GeneratedFile, service *protogen.Service) {
+func (serviceGenerateHelper) generateUnimplementedServerType(gen *protogen.Plugin, file *protogen.File, g *protogen.GeneratedFile, service *protogen.Service, requireUnimplemented bool) {
 	serverType := service.GoName + "Server"
 	mustOrShould := "must"
-	if !*requireUnimplemented {
+	if !requireUnimplemented {
 		mustOrShould = "should"
 	}
 	// Server Unimplemented struct for forward compatibility.
@@ -96,7 +96,7 @@ func (serviceGenerateHelper) generateUnimplementedServerType(gen *protogen.Plugi
 		g.P("return ", nilArg, errorsPackage.Ident("New"), `("method ` + method.GoName + ` not implemented")`)
 		g.P("}")
 	}
-	if *requireUnimplemented {
+	if requireUnimplemented {
 		g.P("func (Unimplemented", serverType, ") mustEmbedUnimplemented", serverType, "() {}")
 	}
 	g.P()
@@ -127,7 +127,7 @@ const fileDescriptorProtoPackageFieldNumber = 2
 const fileDescriptorProtoSyntaxFieldNumber = 12
 
 // generateFile generates a _grpc.pb.go file containing gRPC service definitions.
-func GenerateFile(gen *protogen.Plugin, file *protogen.File) *protogen.GeneratedFile {
+func GenerateFile(gen *protogen.Plugin, file *protogen.File, requireUnimplemented bool) *protogen.GeneratedFile {
 	if len(file.Services) == 0 {
 		return nil
 	}
@@ -137,7 +137,7 @@ func GenerateFile(gen *protogen.Plugin, file *protogen.File) *protogen.Generated
 	genLeadingComments(g, file.Desc.SourceLocations().ByPath(protoreflect.SourcePath{fileDescriptorProtoSyntaxFieldNumber}))
 	g.P("// Code generated by protoc-gen-cofaas-go-grpc. DO NOT EDIT.")
 	g.P("// versions:")
-	g.P("// - protoc-gen-cofaas-go-grpc v", version)
+	g.P("// - protoc-gen-cofaas-go-grpc v", Version)
 	g.P("// - protoc             ", protocVersion(gen))
 	if file.Proto.GetOptions().GetDeprecated() {
 		g.P("// ", file.Desc.Path(), " is a deprecated file.")
@@ -149,7 +149,7 @@ func GenerateFile(gen *protogen.Plugin, file *protogen.File) *protogen.Generated
 	genLeadingComments(g, file.Desc.SourceLocations().ByPath(protoreflect.SourcePath{fileDescriptorProtoPackageFieldNumber}))
 	g.P("package ", file.GoPackageName)
 	g.P()
-	generateFileContent(gen, file, g)
+	generateFileContent(gen, file, g, requireUnimplemented)
 	return g
 }
 
@@ -166,17 +166,17 @@ func protocVersion(gen *protogen.Plugin) string {
 }
 
 // generateFileContent generates the gRPC service definitions, excluding the package statement.
-func generateFileContent(gen *protogen.Plugin, file *protogen.File, g *protogen.GeneratedFile) {
+func generateFileContent(gen *protogen.Plugin, file *protogen.File, g *protogen.GeneratedFile, requireUnimplemented bool) {
 	if len(file.Services) == 0 {
 		return
 	}
 
 	for _, service := range file.Services {
-		genService(gen, file, g, service)
+		genService(gen, file, g, service, requireUnimplemented)
 	}
 }
 
-func genService(gen *protogen.Plugin, file *protogen.File, g *protogen.GeneratedFile, service *protogen.Service) {
+func genService(gen *protogen.Plugin, file *protogen.File, g *protogen.GeneratedFile, service *protogen.Service, requireUnimplemented bool) {
 	// Full methods constants.
 	helper.genFullMethods(g, service)
 
@@ -208,17 +208,11 @@ func genService(gen *protogen.Plugin, file *protogen.File, g *protogen.Generated
 	// implementation of the client structs
 	helper.generateUnimplementedClientStruct(g, clientName)
 	for _, method := range service.Methods {
-		if !method.Desc.IsStreamingServer() && !method.Desc.IsStreamingClient() {
-			// Unary RPC method
-			genUnimplementedClientMethod(gen, file, g, method)
-		} else {
-			// Streaming RPC method
-			panic("Streaming RPC methods are not supported")
-		}
+		genUnimplementedClientMethod(gen, file, g, method)
 	}
 
 	// Client implementation variable
-	g.P("var clientImplementation ", clientName, " = unimplemented", clientName, "{}")
+	g.P("var ", unexport(clientName), "Implementation ", clientName, " = unimplemented", clientName, "{}")
 
 	// // Client structure.
 	// helper.generateClientStruct(g, clientName)
@@ -234,11 +228,11 @@ func genService(gen *protogen.Plugin, file *protogen.File, g *protogen.Generated
 
 	//Set client implementation function
 	g.P("func Set", clientName, "Implementation(impl ", clientName, ") {")
-	g.P("clientImplementation = impl")
+	g.P(unexport(clientName), "Implementation = impl")
 	g.P("}")
 
 	mustOrShould := "must"
-	if !*requireUnimplemented {
+	if !requireUnimplemented {
 		mustOrShould = "should"
 	}
 
@@ -261,18 +255,18 @@ func genService(gen *protogen.Plugin, file *protogen.File, g *protogen.Generated
 		g.P(method.Comments.Leading,
 			serverSignature(g, method))
 	}
-	if *requireUnimplemented {
+	if requireUnimplemented {
 		g.P("mustEmbedUnimplemented", serverType, "()")
 	}
 	g.P("}")
 	g.P()
 
 	// Variable for holding the server implementation
-	g.P("var ServerImplementation ", serverType, " = Unimplemented", serverType, "{}")
+	g.P("var ", serverType, "Implementation ", serverType, " = Unimplemented", serverType, "{}")
 	g.P()
 
 	// Server Unimplemented struct for forward compatibility.
-	helper.generateUnimplementedServerType(gen, file, g, service)
+	helper.generateUnimplementedServerType(gen, file, g, service, requireUnimplemented)
 
 	// Unsafe Server interface to opt-out of forward compatibility.
 	g.P("// Unsafe", serverType, " may be embedded to opt out of forward compatibility for this service.")
@@ -288,11 +282,73 @@ func genService(gen *protogen.Plugin, file *protogen.File, g *protogen.Generated
 	}
 	serviceDescVar := service.GoName + "_ServiceDesc"
 	g.P("func Register", service.GoName, "Server(s interface{}, srv ", serverType, ") {")
-	g.P("ServerImplementation = srv")
+	g.P(serverType, "Implementation = srv")
 	g.P("}")
 	g.P()
 
 	helper.generateServerFunctions(gen, file, g, service, serverType, serviceDescVar)
+
+	for _, method := range service.Methods {
+		if method.Desc.IsStreamingClient() || method.Desc.IsStreamingServer() {
+			genStreamInterfaces(g, method)
+		}
+	}
+}
+
+// genStreamInterfaces generates the stream types used by the client
+// and server APIs of the streaming method. They have the typed methods
+// of the corresponding grpc-go streams, Send, Recv, CloseSend,
+// CloseAndRecv and SendAndClose, and Context. The untyped SendMsg and
+// RecvMsg and the metadata methods Header, Trailer, SetHeader,
+// SendHeader and SetTrailer are left out as metadata is not passed
+// between components. The implementations of the streams are provided
+// by the component glue.
+func genStreamInterfaces(g *protogen.GeneratedFile, method *protogen.Method) {
+	service := method.Parent
+	clientType := service.GoName + "_" + method.GoName + "Client"
+	serverType := service.GoName + "_" + method.GoName + "Server"
+	input := g.QualifiedGoIdent(method.Input.GoIdent)
+	output := g.QualifiedGoIdent(method.Output.GoIdent)
+	clientStreaming := method.Desc.IsStreamingClient()
+	serverStreaming := method.Desc.IsStreamingServer()
+
+	g.P("// ", clientType, " is the client side of a ", method.GoName, " call.")
+	if serverStreaming {
+		g.P("// Recv returns io.EOF once all messages have been received.")
+	}
+	g.P("// Stream metadata, SendMsg and RecvMsg are not supported.")
+	g.P("type ", clientType, " interface {")
+	if clientStreaming {
+		g.P("Send(*", input, ") error")
+	}
+	if clientStreaming && !serverStreaming {
+		g.P("CloseAndRecv() (*", output, ", error)")
+	}
+	if serverStreaming {
+		g.P("Recv() (*", output, ", error)")
+		g.P("CloseSend() error")
+	}
+	g.P("Context() ", contextPackage.Ident("Context"))
+	g.P("}")
+	g.P()
+
+	g.P("// ", serverType, " is the server side of a ", method.GoName, " call.")
+	if clientStreaming {
+		g.P("// Recv returns io.EOF once the client has closed its side of the stream.")
+	}
+	g.P("// Stream metadata, SendMsg and RecvMsg are not supported.")
+	g.P("type ", serverType, " interface {")
+	if serverStreaming {
+		g.P("Send(*", output, ") error")
+	} else {
+		g.P("SendAndClose(*", output, ") error")
+	}
+	if clientStreaming {
+		g.P("Recv() (*", input, ", error)")
+	}
+	g.P("Context() ", contextPackage.Ident("Context"))
+	g.P("}")
+	g.P()
 }
 
 func clientSignature(g *protogen.GeneratedFile, method *protogen.Method) string {
@@ -320,43 +376,6 @@ func genUnimplementedClientMethod(gen *protogen.Plugin, file *protogen.File, g *
 
 }
 
-func genClientMethod(gen *protogen.Plugin, file *protogen.File, g *protogen.GeneratedFile, method *protogen.Method, index int) {
-	service := method.Parent
-	fmSymbol := helper.formatFullMethodSymbol(service, method)
-
-	// TODO: Look at other rpc interface here
-	if method.Desc.Options().(*descriptorpb.MethodOptions).GetDeprecated() {
-		g.P(deprecationComment)
-	}
-	g.P("func (c *", unexport(service.GoName), "Client) ", clientSignature(g, method), "{")
-	if !method.Desc.IsStreamingServer() && !method.Desc.IsStreamingClient() {
-		g.P("out := new(", method.Output.GoIdent, ")")
-		g.P(`err := c.cc.Invoke(ctx, `, fmSymbol, `, in, out, opts...)`)
-		g.P("if err != nil { return nil, err }")
-		g.P("return out, nil")
-		g.P("}")
-		g.P()
-		return
-	}
-	streamType := unexport(service.GoName) + method.GoName + "Client"
-	serviceDescVar := service.GoName + "_ServiceDesc"
-	g.P("stream, err := c.cc.NewStream(ctx, &", serviceDescVar, ".Streams[", index, `], `, fmSymbol, `, opts...)`)
-	g.P("if err != nil { return nil, err }")
-	g.P("x := &", streamType, "{stream}")
-	if !method.Desc.IsStreamingClient() {
-		g.P("if err := x.ClientStream.SendMsg(in); err != nil { return nil, err }")
-		g.P("if err := x.ClientStream.CloseSend(); err != nil { return nil, err }")
-	}
-	g.P("return x, nil")
-	g.P("}")
-	g.P()
-
-	if method.Desc.IsStreamingClient() || method.Desc.IsStreamingServer() {
-		panic("Streaming methods are not supported")
-	}
-
-}
-
 func serverSignature(g *protogen.GeneratedFile, method *protogen.Method) string {
 	var reqArgs []string
 	ret := "error"
@@ -0,0 +1,38 @@
+// Package gengrpc generates the cofaas versions of the gRPC service
+// definitions of protocol files.
+package gengrpc
+
+import (
+	"flag"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// Version is the version of the plugin
+const Version = "1.3.0"
+
+// Plugin generates a _grpc.pb.go file for each file to generate
+type Plugin struct {
+	// Flags holds the parameters of the plugin
+	Flags                flag.FlagSet
+	requireUnimplemented *bool
+}
+
+func New() *Plugin {
+	p := &Plugin{}
+	p.requireUnimplemented = p.Flags.Bool("require_unimplemented_servers", true, "set to false to match legacy behavior")
+	return p
+}
+
+// Run generates the service definitions of the files of gen
+func (p *Plugin) Run(gen *protogen.Plugin) error {
+	gen.SupportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)
+	for _, f := range gen.Files {
+		if !f.Generate {
+			continue
+		}
+		GenerateFile(gen, f, *p.requireUnimplemented)
+	}
+	return nil
+}
@@ -37,35 +37,20 @@ import (
 	"flag"
 	"fmt"
 
+	"github.com/truls/cofaas-go/protogen/grpc/gengrpc"
 	"google.golang.org/protobuf/compiler/protogen"
-	"google.golang.org/protobuf/types/pluginpb"
 )
 
-const version = "1.3.0"
-
-var requireUnimplemented *bool
-
 func main() {
 	showVersion := flag.Bool("version", false, "print the version and exit")
 	flag.Parse()
 	if *showVersion {
-		fmt.Printf("protoc-gen-cofaas-go-grpc %v\n", version)
+		fmt.Printf("protoc-gen-cofaas-go-grpc %v\n", gengrpc.Version)
 		return
 	}
 
-	var flags flag.FlagSet
-	requireUnimplemented = flags.Bool("require_unimplemented_servers", true, "set to false to match legacy behavior")
-
+	p := gengrpc.New()
 	protogen.Options{
-		ParamFunc: flags.Set,
-	}.Run(func(gen *protogen.Plugin) error {
-		gen.SupportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)
-		for _, f := range gen.Files {
-			if !f.Generate {
-				continue
-			}
-			GenerateFile(gen, f)
-		}
-		return nil
-	})
+		ParamFunc: p.Flags.Set,
+	}.Run(p.Run)
 }
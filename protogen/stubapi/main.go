@@ -0,0 +1,33 @@
+// The stubapi command generates the list of the exported API of the
+// stub packages that is used to detect uses of unimplemented APIs.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	c "github.com/truls/cofaas-go"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s <output file> <stub module dirs>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	res, err := c.GenStubAPI(flag.Args()[1:]...)
+	if err != nil {
+		fmt.Printf("Generating the stub API failed %s\n", c.FormatError(err))
+		os.Exit(1)
+	}
+	if err := os.WriteFile(flag.Arg(0), []byte(res), 0644); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
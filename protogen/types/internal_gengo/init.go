@@ -112,8 +112,10 @@ type enumInfo struct {
 
 func newEnumInfo(f *fileInfo, enum *protogen.Enum) *enumInfo {
 	e := &enumInfo{Enum: enum}
-	e.genJSONMethod = true
-	e.genRawDescMethod = true
+	// The JSON and raw descriptor methods depend on the protobuf
+	// runtime which isn't available to cofaas components
+	e.genJSONMethod = false
+	e.genRawDescMethod = false
 	return e
 }
 
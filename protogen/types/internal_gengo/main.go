@@ -33,9 +33,13 @@ var GenerateVersionMarkers = true
 
 // // Standard library dependencies.
 const (
+	base64Package  = protogen.GoImportPath("encoding/base64")
+	fmtPackage     = protogen.GoImportPath("fmt")
+	jsonPackage    = protogen.GoImportPath("encoding/json")
 	mathPackage    = protogen.GoImportPath("math")
 	reflectPackage = protogen.GoImportPath("reflect")
 	sortPackage    = protogen.GoImportPath("sort")
+	strconvPackage = protogen.GoImportPath("strconv")
 	stringsPackage = protogen.GoImportPath("strings")
 	syncPackage    = protogen.GoImportPath("sync")
 	timePackage    = protogen.GoImportPath("time")
@@ -263,8 +267,15 @@ func genEnum(g *protogen.GeneratedFile, f *fileInfo, e *enumInfo) {
 	g.P()
 
 	// String method.
+	//
+	// Resolved through the name map instead of the enum descriptor
+	// since reflection is not available. Unknown values are formatted
+	// as their numeric value.
 	g.P("func (x ", e.GoIdent, ") String() string {")
-	g.P("return ", protoimplPackage.Ident("X"), ".EnumStringOf(x.Descriptor(), ", protoreflectPackage.Ident("EnumNumber"), "(x))")
+	g.P("if name, ok := ", e.GoIdent.GoName+"_name", "[int32(x)]; ok {")
+	g.P("return name")
+	g.P("}")
+	g.P("return ", strconvPackage.Ident("Itoa"), "(int(x))")
 	g.P("}")
 	g.P()
 
@@ -315,10 +326,11 @@ func genMessage(g *protogen.GeneratedFile, f *fileInfo, m *messageInfo) {
 	g.P("}")
 	g.P()
 
-	// genMessageKnownFunctions(g, f, m)
-	// genMessageDefaultDecls(g, f, m)
+	genMessageKnownFunctions(g, f, m)
+	genMessageDefaultDecls(g, f, m)
 	// genMessageMethods(g, f, m)
-	// genMessageOneofWrapperTypes(g, f, m)
+	genMessageGetterMethods(g, f, m)
+	genMessageOneofWrapperTypes(g, f, m)
 }
 
 func genMessageFields(g *protogen.GeneratedFile, f *fileInfo, m *messageInfo) {
@@ -350,6 +362,30 @@ func genMessageInternalFields(g *protogen.GeneratedFile, f *fileInfo, m *message
 }
 
 func genMessageField(g *protogen.GeneratedFile, f *fileInfo, m *messageInfo, field *protogen.Field, sf *structFields) {
+	if oneof := field.Oneof; oneof != nil && !oneof.Desc.IsSynthetic() {
+		// It would be a bit simpler to iterate over the oneofs below,
+		// but generating the field here keeps the contents of the Go
+		// struct in the same order as the contents of the source
+		// .proto file.
+		if oneof.Fields[0] != field {
+			return // only generate for first appearance
+		}
+
+		g.Annotate(m.GoIdent.GoName+"."+oneof.GoName, oneof.Location)
+		leadingComments := oneof.Comments.Leading
+		if leadingComments != "" {
+			leadingComments += "\n"
+		}
+		ss := []string{fmt.Sprintf(" Types that are assignable to %s:\n", oneof.GoName)}
+		for _, field := range oneof.Fields {
+			ss = append(ss, "\t*"+field.GoIdent.GoName+"\n")
+		}
+		leadingComments += protogen.Comments(strings.Join(ss, ""))
+		g.P(leadingComments,
+			oneof.GoName, " ", oneofInterfaceName(oneof))
+		sf.append(oneof.GoName)
+		return
+	}
 	goType, pointer := fieldGoType(g, f, field)
 	if pointer {
 		goType = "*" + goType
@@ -673,9 +709,7 @@ func genMessageOneofWrapperTypes(g *protogen.GeneratedFile, f *fileInfo, m *mess
 			g.Annotate(field.GoIdent.GoName+"."+field.GoName, field.Location)
 			g.P("type ", field.GoIdent, " struct {")
 			goType, _ := fieldGoType(g, f, field)
-			tags := structTags{
-				{"protobuf", fieldProtobufTagValue(field)},
-			}
+			tags := structTags{}
 			if m.isTracked {
 				tags = append(tags, gotrackTags...)
 			}
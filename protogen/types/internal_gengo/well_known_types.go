@@ -5,6 +5,8 @@
 package internal_gengo
 
 import (
+	"strings"
+
 	"google.golang.org/protobuf/compiler/protogen"
 	"github.com/truls/cofaas-go/protogen/types/internal_gengo/genid"
 )
@@ -23,85 +25,14 @@ func genPackageKnownComment(f *fileInfo) protogen.Comments {
  It is functionally a tuple of the full name of the remote message type and
  the serialized bytes of the remote message value.
 
+ Since this package does not depend on the protobuf runtime, it cannot
+ marshal or unmarshal the remote message value. The type URL and the
+ serialized value are available through the TypeUrl and Value fields and
+ the full name of the remote message type through the MessageName method:
 
- Constructing an Any
-
- An Any message containing another message value is constructed using New:
-
-	any, err := anypb.New(m)
-	if err != nil {
-		... // handle error
-	}
-	... // make use of any
-
-
- Unmarshaling an Any
-
- With a populated Any message, the underlying message can be serialized into
- a remote concrete message value in a few ways.
-
- If the exact concrete type is known, then a new (or pre-existing) instance
- of that message can be passed to the UnmarshalTo method:
-
-	m := new(foopb.MyMessage)
-	if err := any.UnmarshalTo(m); err != nil {
-		... // handle error
-	}
-	... // make use of m
-
- If the exact concrete type is not known, then the UnmarshalNew method can be
- used to unmarshal the contents into a new instance of the remote message type:
-
-	m, err := any.UnmarshalNew()
-	if err != nil {
-		... // handle error
-	}
-	... // make use of m
-
- UnmarshalNew uses the global type registry to resolve the message type and
- construct a new instance of that message to unmarshal into. In order for a
- message type to appear in the global registry, the Go type representing that
- protobuf message type must be linked into the Go binary. For messages
- generated by protoc-gen-go, this is achieved through an import of the
- generated Go package representing a .proto file.
-
- A common pattern with UnmarshalNew is to use a type switch with the resulting
- proto.Message value:
-
-	switch m := m.(type) {
-	case *foopb.MyMessage:
-		... // make use of m as a *foopb.MyMessage
-	case *barpb.OtherMessage:
-		... // make use of m as a *barpb.OtherMessage
-	case *bazpb.SomeMessage:
-		... // make use of m as a *bazpb.SomeMessage
-	}
-
- This pattern ensures that the generated packages containing the message types
- listed in the case clauses are linked into the Go binary and therefore also
- registered in the global registry.
-
-
- Type checking an Any
-
- In order to type check whether an Any message represents some other message,
- then use the MessageIs method:
-
-	if any.MessageIs((*foopb.MyMessage)(nil)) {
-		... // make use of any, knowing that it contains a foopb.MyMessage
+	if any.MessageName() == "foo.MyMessage" {
+		... // make use of any.Value
 	}
-
- The MessageIs method can also be used with an allocated instance of the target
- message type if the intention is to unmarshal into it if the type matches:
-
-	m := new(foopb.MyMessage)
-	if any.MessageIs(m) {
-		if err := any.UnmarshalTo(m); err != nil {
-			... // handle error
-		}
-		... // make use of m
-	}
-
 `
 	case genid.File_google_protobuf_timestamp_proto:
 		return ` Package timestamppb contains generated types for ` + genid.File_google_protobuf_timestamp_proto + `.
@@ -291,42 +222,431 @@ func genPackageKnownComment(f *fileInfo) protogen.Comments {
 
  The New function is used construct a FieldMask:
 
-	var messageType *descriptorpb.DescriptorProto
-	fm, err := fieldmaskpb.New(messageType, "field.name", "field.number")
-	if err != nil {
-		... // handle error
-	}
+	fm := fieldmaskpb.New("field.name", "field.number")
 	... // make use of fm
 
- The "field.name" and "field.number" paths are valid paths according to the
- google.protobuf.DescriptorProto message. Use of a path that does not correlate
- to valid fields reachable from DescriptorProto would result in an error.
-
  Once a FieldMask message has been constructed,
  the Append method can be used to insert additional paths to the path set:
 
-	var messageType *descriptorpb.DescriptorProto
-	if err := fm.Append(messageType, "options"); err != nil {
-		... // handle error
-	}
-
+	fm.Append("options")
 
- Type checking a FieldMask
-
- In order to verify that a FieldMask represents a set of fields that are
- reachable from some target message type, use the IsValid method:
-
-	var messageType *descriptorpb.DescriptorProto
-	if fm.IsValid(messageType) {
-		... // make use of fm
-	}
-
- IsValid needs to be passed the target message type as an input since the
- FieldMask message itself does not store the message type that the set of paths
- are for.
+ Since this package does not depend on the protobuf runtime, the paths
+ are not validated against the target message type.
 `
 	default:
 		return ""
 	}
 }
 
+
+// genMessageKnownFunctions generates helpers for the well-known types
+// similar to those provided by protoc-gen-go. Unlike the upstream
+// helpers, these are implemented without the protobuf runtime and
+// reflection such that the bundled well-known type packages can be
+// used in components.
+func genMessageKnownFunctions(g *protogen.GeneratedFile, f *fileInfo, m *messageInfo) {
+	switch m.Desc.FullName() {
+	case genid.Any_message_fullname:
+		g.P("// MessageName reports the full name of the message type stored in x")
+		g.P("// as derived from its type URL. An empty name is returned if the")
+		g.P("// type URL is invalid.")
+		g.P("func (x *Any) MessageName() string {")
+		g.P("	url := x.GetTypeUrl()")
+		g.P("	name := url")
+		g.P("	if i := ", stringsPackage.Ident("LastIndexByte"), "(url, '/'); i >= 0 {")
+		g.P("		name = name[i+len(\"/\"):]")
+		g.P("	}")
+		g.P("	return name")
+		g.P("}")
+		g.P()
+
+	case genid.Timestamp_message_fullname:
+		g.P("// Now constructs a new Timestamp from the current time.")
+		g.P("func Now() *Timestamp {")
+		g.P("	return New(", timePackage.Ident("Now"), "())")
+		g.P("}")
+		g.P()
+
+		g.P("// New constructs a new Timestamp from the provided time.Time.")
+		g.P("func New(t ", timePackage.Ident("Time"), ") *Timestamp {")
+		g.P("	return &Timestamp{Seconds: int64(t.Unix()), Nanos: int32(t.Nanosecond())}")
+		g.P("}")
+		g.P()
+
+		g.P("// AsTime converts x to a time.Time.")
+		g.P("func (x *Timestamp) AsTime() ", timePackage.Ident("Time"), " {")
+		g.P("	return ", timePackage.Ident("Unix"), "(int64(x.GetSeconds()), int64(x.GetNanos())).UTC()")
+		g.P("}")
+		g.P()
+
+		g.P("// IsValid reports whether the timestamp is valid.")
+		g.P("// It is equivalent to CheckValid == nil.")
+		g.P("func (x *Timestamp) IsValid() bool {")
+		g.P("	return x.CheckValid() == nil")
+		g.P("}")
+		g.P()
+
+		g.P("// CheckValid returns an error if the timestamp is invalid.")
+		g.P("// In particular, it checks whether the value represents a date that is")
+		g.P("// in the range of 0001-01-01T00:00:00Z to 9999-12-31T23:59:59Z inclusive.")
+		g.P("// An error is reported for a nil Timestamp.")
+		g.P("func (x *Timestamp) CheckValid() error {")
+		g.P("	const minTimestamp = -62135596800  // Seconds between 1970-01-01T00:00:00Z and 0001-01-01T00:00:00Z, inclusive")
+		g.P("	const maxTimestamp = +253402300799 // Seconds between 1970-01-01T00:00:00Z and 9999-12-31T23:59:59Z, inclusive")
+		g.P("	secs := x.GetSeconds()")
+		g.P("	nanos := x.GetNanos()")
+		g.P("	switch {")
+		g.P("	case x == nil:")
+		g.P("		return ", fmtPackage.Ident("Errorf"), "(\"invalid nil Timestamp\")")
+		g.P("	case secs < minTimestamp:")
+		g.P("		return ", fmtPackage.Ident("Errorf"), "(\"timestamp (%d, %d) before 0001-01-01\", secs, nanos)")
+		g.P("	case secs > maxTimestamp:")
+		g.P("		return ", fmtPackage.Ident("Errorf"), "(\"timestamp (%d, %d) after 9999-12-31\", secs, nanos)")
+		g.P("	case nanos < 0 || nanos >= 1e9:")
+		g.P("		return ", fmtPackage.Ident("Errorf"), "(\"timestamp (%d, %d) has out-of-range nanos\", secs, nanos)")
+		g.P("	}")
+		g.P("	return nil")
+		g.P("}")
+		g.P()
+
+	case genid.Duration_message_fullname:
+		g.P("// New constructs a new Duration from the provided time.Duration.")
+		g.P("func New(d ", timePackage.Ident("Duration"), ") *Duration {")
+		g.P("	nanos := d.Nanoseconds()")
+		g.P("	secs := nanos / 1e9")
+		g.P("	nanos -= secs * 1e9")
+		g.P("	return &Duration{Seconds: int64(secs), Nanos: int32(nanos)}")
+		g.P("}")
+		g.P()
+
+		g.P("// AsDuration converts x to a time.Duration,")
+		g.P("// returning the closest duration value in the event of overflow.")
+		g.P("func (x *Duration) AsDuration() ", timePackage.Ident("Duration"), " {")
+		g.P("	secs := x.GetSeconds()")
+		g.P("	nanos := x.GetNanos()")
+		g.P("	d := ", timePackage.Ident("Duration"), "(secs) * ", timePackage.Ident("Second"))
+		g.P("	overflow := d/", timePackage.Ident("Second"), " != ", timePackage.Ident("Duration"), "(secs)")
+		g.P("	d += ", timePackage.Ident("Duration"), "(nanos) * ", timePackage.Ident("Nanosecond"))
+		g.P("	overflow = overflow || (secs < 0 && nanos < 0 && d > 0)")
+		g.P("	overflow = overflow || (secs > 0 && nanos > 0 && d < 0)")
+		g.P("	if overflow {")
+		g.P("		switch {")
+		g.P("		case secs < 0:")
+		g.P("			return ", timePackage.Ident("Duration"), "(", mathPackage.Ident("MinInt64"), ")")
+		g.P("		case secs > 0:")
+		g.P("			return ", timePackage.Ident("Duration"), "(", mathPackage.Ident("MaxInt64"), ")")
+		g.P("		}")
+		g.P("	}")
+		g.P("	return d")
+		g.P("}")
+		g.P()
+
+		g.P("// IsValid reports whether the duration is valid.")
+		g.P("// It is equivalent to CheckValid == nil.")
+		g.P("func (x *Duration) IsValid() bool {")
+		g.P("	return x.CheckValid() == nil")
+		g.P("}")
+		g.P()
+
+		g.P("// CheckValid returns an error if the duration is invalid.")
+		g.P("// In particular, it checks whether the value is within the range of")
+		g.P("// -10000 years to +10000 years inclusive.")
+		g.P("// An error is reported for a nil Duration.")
+		g.P("func (x *Duration) CheckValid() error {")
+		g.P("	const absDuration = 315576000000 // 10000yr * 365.25day/yr * 24hr/day * 60min/hr * 60sec/min")
+		g.P("	secs := x.GetSeconds()")
+		g.P("	nanos := x.GetNanos()")
+		g.P("	switch {")
+		g.P("	case x == nil:")
+		g.P("		return ", fmtPackage.Ident("Errorf"), "(\"invalid nil Duration\")")
+		g.P("	case secs < -absDuration || secs > +absDuration:")
+		g.P("		return ", fmtPackage.Ident("Errorf"), "(\"duration (%d, %d) exceeds -10000 or +10000 years\", secs, nanos)")
+		g.P("	case nanos <= -1e9 || nanos >= +1e9:")
+		g.P("		return ", fmtPackage.Ident("Errorf"), "(\"duration (%d, %d) has out-of-range nanos\", secs, nanos)")
+		g.P("	case (secs > 0 && nanos < 0) || (secs < 0 && nanos > 0):")
+		g.P("		return ", fmtPackage.Ident("Errorf"), "(\"duration (%d, %d) has seconds and nanos with different signs\", secs, nanos)")
+		g.P("	}")
+		g.P("	return nil")
+		g.P("}")
+		g.P()
+
+	case genid.Struct_message_fullname:
+		g.P("// NewStruct constructs a Struct from a general-purpose Go map.")
+		g.P("// The map keys must be valid UTF-8.")
+		g.P("// The map values are converted using NewValue.")
+		g.P("func NewStruct(v map[string]interface{}) (*Struct, error) {")
+		g.P("	x := &Struct{Fields: make(map[string]*Value, len(v))}")
+		g.P("	for k, v := range v {")
+		g.P("		if !", utf8Package.Ident("ValidString"), "(k) {")
+		g.P("			return nil, ", fmtPackage.Ident("Errorf"), "(\"invalid UTF-8 in string: %q\", k)")
+		g.P("		}")
+		g.P("		var err error")
+		g.P("		x.Fields[k], err = NewValue(v)")
+		g.P("		if err != nil {")
+		g.P("			return nil, err")
+		g.P("		}")
+		g.P("	}")
+		g.P("	return x, nil")
+		g.P("}")
+		g.P()
+
+		g.P("// AsMap converts x to a general-purpose Go map.")
+		g.P("// The map values are converted by calling Value.AsInterface.")
+		g.P("func (x *Struct) AsMap() map[string]interface{} {")
+		g.P("	f := x.GetFields()")
+		g.P("	vs := make(map[string]interface{}, len(f))")
+		g.P("	for k, v := range f {")
+		g.P("		vs[k] = v.AsInterface()")
+		g.P("	}")
+		g.P("	return vs")
+		g.P("}")
+		g.P()
+
+		genJSONMethods(g, "Struct", "map[string]interface{}", "NewStruct", "AsMap")
+
+	case genid.Value_message_fullname:
+		g.P("// NewValue constructs a Value from a general-purpose Go interface.")
+		g.P("//")
+		g.P("//	╔════════════════════════╤════════════════════════════════════════════╗")
+		g.P("//	║ Go type                │ Conversion                                 ║")
+		g.P("//	╠════════════════════════╪════════════════════════════════════════════╣")
+		g.P("//	║ nil                    │ stored as NullValue                        ║")
+		g.P("//	║ bool                   │ stored as BoolValue                        ║")
+		g.P("//	║ int, int32, int64      │ stored as NumberValue                      ║")
+		g.P("//	║ uint, uint32, uint64   │ stored as NumberValue                      ║")
+		g.P("//	║ float32, float64       │ stored as NumberValue                      ║")
+		g.P("//	║ string                 │ stored as StringValue; must be valid UTF-8 ║")
+		g.P("//	║ []byte                 │ stored as StringValue; base64-encoded      ║")
+		g.P("//	║ map[string]interface{} │ stored as StructValue                      ║")
+		g.P("//	║ []interface{}          │ stored as ListValue                        ║")
+		g.P("//	╚════════════════════════╧════════════════════════════════════════════╝")
+		g.P("//")
+		g.P("// When converting an int64 or uint64 to a NumberValue, numeric precision loss")
+		g.P("// is possible since they are stored as a float64.")
+		g.P("func NewValue(v interface{}) (*Value, error) {")
+		g.P("	switch v := v.(type) {")
+		g.P("	case nil:")
+		g.P("		return NewNullValue(), nil")
+		g.P("	case bool:")
+		g.P("		return NewBoolValue(v), nil")
+		g.P("	case int:")
+		g.P("		return NewNumberValue(float64(v)), nil")
+		g.P("	case int32:")
+		g.P("		return NewNumberValue(float64(v)), nil")
+		g.P("	case int64:")
+		g.P("		return NewNumberValue(float64(v)), nil")
+		g.P("	case uint:")
+		g.P("		return NewNumberValue(float64(v)), nil")
+		g.P("	case uint32:")
+		g.P("		return NewNumberValue(float64(v)), nil")
+		g.P("	case uint64:")
+		g.P("		return NewNumberValue(float64(v)), nil")
+		g.P("	case float32:")
+		g.P("		return NewNumberValue(float64(v)), nil")
+		g.P("	case float64:")
+		g.P("		return NewNumberValue(float64(v)), nil")
+		g.P("	case string:")
+		g.P("		if !", utf8Package.Ident("ValidString"), "(v) {")
+		g.P("			return nil, ", fmtPackage.Ident("Errorf"), "(\"invalid UTF-8 in string: %q\", v)")
+		g.P("		}")
+		g.P("		return NewStringValue(v), nil")
+		g.P("	case []byte:")
+		g.P("		s := ", base64Package.Ident("StdEncoding"), ".EncodeToString(v)")
+		g.P("		return NewStringValue(s), nil")
+		g.P("	case map[string]interface{}:")
+		g.P("		v2, err := NewStruct(v)")
+		g.P("		if err != nil {")
+		g.P("			return nil, err")
+		g.P("		}")
+		g.P("		return NewStructValue(v2), nil")
+		g.P("	case []interface{}:")
+		g.P("		v2, err := NewList(v)")
+		g.P("		if err != nil {")
+		g.P("			return nil, err")
+		g.P("		}")
+		g.P("		return NewListValue(v2), nil")
+		g.P("	default:")
+		g.P("		return nil, ", fmtPackage.Ident("Errorf"), "(\"invalid type: %T\", v)")
+		g.P("	}")
+		g.P("}")
+		g.P()
+
+		g.P("// NewNullValue constructs a new null Value.")
+		g.P("func NewNullValue() *Value {")
+		g.P("	return &Value{Kind: &Value_NullValue{NullValue: NullValue_NULL_VALUE}}")
+		g.P("}")
+		g.P()
+
+		g.P("// NewBoolValue constructs a new boolean Value.")
+		g.P("func NewBoolValue(v bool) *Value {")
+		g.P("	return &Value{Kind: &Value_BoolValue{BoolValue: v}}")
+		g.P("}")
+		g.P()
+
+		g.P("// NewNumberValue constructs a new number Value.")
+		g.P("func NewNumberValue(v float64) *Value {")
+		g.P("	return &Value{Kind: &Value_NumberValue{NumberValue: v}}")
+		g.P("}")
+		g.P()
+
+		g.P("// NewStringValue constructs a new string Value.")
+		g.P("func NewStringValue(v string) *Value {")
+		g.P("	return &Value{Kind: &Value_StringValue{StringValue: v}}")
+		g.P("}")
+		g.P()
+
+		g.P("// NewStructValue constructs a new struct Value.")
+		g.P("func NewStructValue(v *Struct) *Value {")
+		g.P("	return &Value{Kind: &Value_StructValue{StructValue: v}}")
+		g.P("}")
+		g.P()
+
+		g.P("// NewListValue constructs a new list Value.")
+		g.P("func NewListValue(v *ListValue) *Value {")
+		g.P("	return &Value{Kind: &Value_ListValue{ListValue: v}}")
+		g.P("}")
+		g.P()
+
+		g.P("// AsInterface converts x to a general-purpose Go interface.")
+		g.P("//")
+		g.P("// Calling Value.MarshalJSON and \"encoding/json\".Marshal on this output produce")
+		g.P("// semantically equivalent JSON (assuming no errors occur).")
+		g.P("//")
+		g.P("// Floating-point values (i.e., \"NaN\", \"Infinity\", and \"-Infinity\") are")
+		g.P("// converted as strings to remain compatible with MarshalJSON.")
+		g.P("func (x *Value) AsInterface() interface{} {")
+		g.P("	switch v := x.GetKind().(type) {")
+		g.P("	case *Value_NumberValue:")
+		g.P("		if v != nil {")
+		g.P("			switch {")
+		g.P("			case ", mathPackage.Ident("IsNaN"), "(v.NumberValue):")
+		g.P("				return \"NaN\"")
+		g.P("			case ", mathPackage.Ident("IsInf"), "(v.NumberValue, +1):")
+		g.P("				return \"Infinity\"")
+		g.P("			case ", mathPackage.Ident("IsInf"), "(v.NumberValue, -1):")
+		g.P("				return \"-Infinity\"")
+		g.P("			default:")
+		g.P("				return v.NumberValue")
+		g.P("			}")
+		g.P("		}")
+		g.P("	case *Value_StringValue:")
+		g.P("		if v != nil {")
+		g.P("			return v.StringValue")
+		g.P("		}")
+		g.P("	case *Value_BoolValue:")
+		g.P("		if v != nil {")
+		g.P("			return v.BoolValue")
+		g.P("		}")
+		g.P("	case *Value_StructValue:")
+		g.P("		if v != nil {")
+		g.P("			return v.StructValue.AsMap()")
+		g.P("		}")
+		g.P("	case *Value_ListValue:")
+		g.P("		if v != nil {")
+		g.P("			return v.ListValue.AsSlice()")
+		g.P("		}")
+		g.P("	}")
+		g.P("	return nil")
+		g.P("}")
+		g.P()
+
+		genJSONMethods(g, "Value", "interface{}", "NewValue", "AsInterface")
+
+	case genid.ListValue_message_fullname:
+		g.P("// NewList constructs a ListValue from a general-purpose Go slice.")
+		g.P("// The slice elements are converted using NewValue.")
+		g.P("func NewList(v []interface{}) (*ListValue, error) {")
+		g.P("	x := &ListValue{Values: make([]*Value, len(v))}")
+		g.P("	for i, v := range v {")
+		g.P("		var err error")
+		g.P("		x.Values[i], err = NewValue(v)")
+		g.P("		if err != nil {")
+		g.P("			return nil, err")
+		g.P("		}")
+		g.P("	}")
+		g.P("	return x, nil")
+		g.P("}")
+		g.P()
+
+		g.P("// AsSlice converts x to a general-purpose Go slice.")
+		g.P("// The slice elements are converted by calling Value.AsInterface.")
+		g.P("func (x *ListValue) AsSlice() []interface{} {")
+		g.P("	vals := x.GetValues()")
+		g.P("	vs := make([]interface{}, len(vals))")
+		g.P("	for i, v := range vals {")
+		g.P("		vs[i] = v.AsInterface()")
+		g.P("	}")
+		g.P("	return vs")
+		g.P("}")
+		g.P()
+
+		genJSONMethods(g, "ListValue", "[]interface{}", "NewList", "AsSlice")
+
+	case genid.FieldMask_message_fullname:
+		g.P("// New constructs a field mask from a list of paths. Unlike the")
+		g.P("// upstream implementation, the paths are not validated against a")
+		g.P("// message type.")
+		g.P("func New(paths ...string) *FieldMask {")
+		g.P("	return &FieldMask{Paths: append([]string(nil), paths...)}")
+		g.P("}")
+		g.P()
+
+		g.P("// Append appends a list of paths to the mask.")
+		g.P("func (x *FieldMask) Append(paths ...string) {")
+		g.P("	x.Paths = append(x.Paths, paths...)")
+		g.P("}")
+		g.P()
+
+	case genid.BoolValue_message_fullname,
+		genid.Int32Value_message_fullname,
+		genid.Int64Value_message_fullname,
+		genid.UInt32Value_message_fullname,
+		genid.UInt64Value_message_fullname,
+		genid.FloatValue_message_fullname,
+		genid.DoubleValue_message_fullname,
+		genid.StringValue_message_fullname,
+		genid.BytesValue_message_fullname:
+		funcName := strings.TrimSuffix(m.GoIdent.GoName, "Value")
+		typeName := strings.ToLower(funcName)
+		switch typeName {
+		case "float":
+			typeName = "float32"
+		case "double":
+			typeName = "float64"
+		case "bytes":
+			typeName = "[]byte"
+		}
+
+		g.P("// ", funcName, " stores v in a new ", m.GoIdent, " and returns a pointer to it.")
+		g.P("func ", funcName, "(v ", typeName, ") *", m.GoIdent, " {")
+		g.P("	return &", m.GoIdent, "{Value: v}")
+		g.P("}")
+		g.P()
+	}
+}
+
+// genJSONMethods generates MarshalJSON and UnmarshalJSON methods for
+// the struct.proto message name which is converted to and from the Go
+// type goType using the function newFunc and the method asMethod.
+func genJSONMethods(g *protogen.GeneratedFile, name string, goType string, newFunc string, asMethod string) {
+	g.P("// MarshalJSON marshals x as the JSON value it represents.")
+	g.P("func (x *", name, ") MarshalJSON() ([]byte, error) {")
+	g.P("	return ", jsonPackage.Ident("Marshal"), "(x.", asMethod, "())")
+	g.P("}")
+	g.P()
+
+	g.P("// UnmarshalJSON sets x to the message representing the JSON value b.")
+	g.P("func (x *", name, ") UnmarshalJSON(b []byte) error {")
+	g.P("	var v ", goType)
+	g.P("	if err := ", jsonPackage.Ident("Unmarshal"), "(b, &v); err != nil {")
+	g.P("		return err")
+	g.P("	}")
+	g.P("	res, err := ", newFunc, "(v)")
+	g.P("	if err != nil {")
+	g.P("		return err")
+	g.P("	}")
+	g.P("	*x = *res")
+	g.P("	return nil")
+	g.P("}")
+	g.P()
+}
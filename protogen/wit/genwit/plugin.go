@@ -0,0 +1,40 @@
+// Package genwit generates WIT packages describing the services of
+// the export and import protocols of a component.
+package genwit
+
+import (
+	"flag"
+
+	"github.com/truls/cofaas-go/protogen/witnames"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// Version is the version of the plugin
+const Version = "1.3.0"
+
+// Plugin generates a WIT package for the export and import protocols
+type Plugin struct {
+	// Flags holds the parameters of the plugin
+	Flags    flag.FlagSet
+	services witnames.ServiceList
+}
+
+func New() *Plugin {
+	p := &Plugin{}
+	p.Flags.Var(&p.services, "services", "a service of the export protocol to export. May be repeated. All services are exported if not set")
+	return p
+}
+
+// Run generates a WIT package for the files of gen. The first file to
+// generate is the export protocol and the remaining files are the
+// import protocols.
+func (p *Plugin) Run(gen *protogen.Plugin) error {
+	gen.SupportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)
+	exportFile, importFiles, err := witnames.ExportImportFiles(gen)
+	if err != nil {
+		return err
+	}
+	GenerateFile(gen, exportFile, p.services, importFiles)
+	return nil
+}
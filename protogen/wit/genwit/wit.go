@@ -0,0 +1,328 @@
+package genwit
+
+import (
+	"fmt"
+
+	"github.com/truls/cofaas-go/protogen/witnames"
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+// witInterface collects the WIT declarations generated for a single
+// service
+type witInterface struct {
+	name    string
+	service *protogen.Service
+	names   *witnames.Names
+	// Messages in dependency order, i.e., a message is always
+	// preceded by the messages used in its fields
+	messages []*protogen.Message
+	enums    []*protogen.Enum
+	visiting map[*protogen.Message]bool
+	visited  map[*protogen.Message]bool
+	enumSeen map[*protogen.Enum]bool
+	// Whether the interface uses the types of struct.proto
+	valueNodes bool
+}
+
+// GenerateFile generates a .wit file containing a world which exports
+// the services of exportFile named in services, or all of its services
+// if services is empty, and imports the services of importFiles
+func GenerateFile(gen *protogen.Plugin, exportFile *protogen.File, services []string, importFiles []*protogen.File) *protogen.GeneratedFile {
+	g := gen.NewGeneratedFile("component.wit", exportFile.GoImportPath)
+	g.P("// Code generated by protoc-gen-cofaas-wit. DO NOT EDIT.")
+	g.P("// versions:")
+	g.P("// - protoc-gen-cofaas-wit v", Version)
+	g.P("// - protoc             ", protocVersion(gen))
+	g.P()
+	g.P("package ", witnames.Namespace, ":", witnames.Package)
+	g.P()
+
+	exportSvcs, err := witnames.SelectServices(exportFile, services)
+	if err != nil {
+		gen.Error(err)
+		return nil
+	}
+	var importSvcs []*protogen.Service
+	for _, importFile := range importFiles {
+		svcs, err := witnames.SelectServices(importFile, nil)
+		if err != nil {
+			gen.Error(err)
+			return nil
+		}
+		importSvcs = append(importSvcs, svcs...)
+	}
+
+	names := make(map[string]bool)
+	newIfaces := func(svcs []*protogen.Service) ([]*witInterface, error) {
+		var res []*witInterface
+		for _, svc := range svcs {
+			iface, err := newWitInterface(svc)
+			if err != nil {
+				return nil, err
+			}
+			if names[iface.name] {
+				return nil, fmt.Errorf("more than one service is named %s", iface.name)
+			}
+			names[iface.name] = true
+			res = append(res, iface)
+		}
+		return res, nil
+	}
+	exportIfaces, err := newIfaces(exportSvcs)
+	if err != nil {
+		gen.Error(err)
+		return nil
+	}
+	importIfaces, err := newIfaces(importSvcs)
+	if err != nil {
+		gen.Error(err)
+		return nil
+	}
+
+	for _, iface := range exportIfaces {
+		genInterface(iface, g)
+	}
+	for _, iface := range importIfaces {
+		genInterface(iface, g)
+	}
+
+	g.P("world ", witnames.World, " {")
+	for _, importIface := range importIfaces {
+		g.P("  import ", importIface.name)
+	}
+	for _, exportIface := range exportIfaces {
+		g.P("  export ", exportIface.name)
+	}
+	g.P("}")
+	return g
+}
+
+func newWitInterface(svc *protogen.Service) (*witInterface, error) {
+	iface := &witInterface{
+		name:     witnames.Ident(svc.GoName),
+		service:  svc,
+		names:    witnames.NewNames(svc),
+		visiting: make(map[*protogen.Message]bool),
+		visited:  make(map[*protogen.Message]bool),
+		enumSeen: make(map[*protogen.Enum]bool),
+	}
+	for _, m := range svc.Methods {
+		if m.Desc.IsStreamingClient() && witnames.IsUnit(m.Input) {
+			return nil, fmt.Errorf("streaming method %s cannot stream %s which has no fields",
+				m.Desc.FullName(), m.Input.Desc.FullName())
+		}
+		if m.Desc.IsStreamingServer() && witnames.IsUnit(m.Output) {
+			return nil, fmt.Errorf("streaming method %s cannot stream %s which has no fields",
+				m.Desc.FullName(), m.Output.Desc.FullName())
+		}
+		for _, msg := range []*protogen.Message{m.Input, m.Output} {
+			if witnames.IsUnit(msg) {
+				continue
+			}
+			if err := iface.addMessage(msg); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return iface, nil
+}
+
+// addMessage adds msg and all messages it depends on to the
+// interface
+func (i *witInterface) addMessage(msg *protogen.Message) error {
+	if witnames.IsValueTree(msg) {
+		i.valueNodes = true
+	}
+	if _, ok := witnames.KnownType(msg); ok || i.visited[msg] {
+		return nil
+	}
+	if i.visiting[msg] {
+		return fmt.Errorf("message %s is recursive which cannot be represented in WIT", msg.Desc.FullName())
+	}
+	i.visiting[msg] = true
+	for _, f := range msg.Fields {
+		if _, err := i.fieldWitType(f); err != nil {
+			return err
+		}
+		if f.Desc.IsMap() {
+			// Only the value of a map entry can refer to other types
+			f = f.Message.Fields[1]
+		}
+		if f.Message != nil {
+			if err := i.addMessage(f.Message); err != nil {
+				return err
+			}
+		}
+		if f.Enum != nil && !i.enumSeen[f.Enum] {
+			i.enumSeen[f.Enum] = true
+			i.enums = append(i.enums, f.Enum)
+		}
+	}
+	i.visiting[msg] = false
+	i.visited[msg] = true
+	i.messages = append(i.messages, msg)
+	return nil
+}
+
+func genInterface(iface *witInterface, g *protogen.GeneratedFile) {
+	g.P("interface ", iface.name, " {")
+	for _, enum := range iface.enums {
+		g.P("  enum ", witnames.Ident(iface.names.Name(enum.GoIdent)), " {")
+		for _, v := range witnames.EnumValues(enum) {
+			g.P("    ", witnames.EnumCase(v), ",")
+		}
+		g.P("  }")
+		g.P()
+	}
+	if iface.valueNodes {
+		genValueNode(g)
+	}
+	for _, msg := range iface.messages {
+		for _, oneof := range msg.Oneofs {
+			if !oneof.Desc.IsSynthetic() {
+				iface.genVariant(oneof, g)
+			}
+		}
+		g.P("  record ", iface.names.MessageType(msg), " {")
+		for _, f := range msg.Fields {
+			if oneof := f.Oneof; oneof != nil && !oneof.Desc.IsSynthetic() {
+				// An unset oneof is represented by none
+				if oneof.Fields[0] == f {
+					g.P("    ", witnames.Ident(string(oneof.Desc.Name())), ": option<", witnames.Ident(iface.names.Name(oneof.GoIdent)), ">,")
+				}
+				continue
+			}
+			// Field types are validated by addMessage
+			typ, _ := iface.fieldWitType(f)
+			g.P("    ", witnames.Ident(string(f.Desc.Name())), ": ", typ, ",")
+		}
+		g.P("  }")
+		g.P()
+	}
+	for _, m := range iface.service.Methods {
+		if m.Desc.IsStreamingClient() {
+			iface.genStreamResource(m, g)
+		}
+	}
+	g.P("  init-component: func()")
+	for _, m := range iface.service.Methods {
+		if m.Desc.IsStreamingClient() {
+			continue
+		}
+		// Messages without fields are omitted from the signature
+		param, res := "", "_"
+		if !witnames.IsUnit(m.Input) {
+			param = "arg: " + iface.names.MessageType(m.Input)
+		}
+		if !witnames.IsUnit(m.Output) {
+			res = iface.names.MessageType(m.Output)
+		}
+		// The messages sent on a server stream are returned all at once
+		if m.Desc.IsStreamingServer() {
+			res = "list<" + res + ">"
+		}
+		g.P("  ", witnames.Ident(m.GoName), ": func(", param, ") -> result<", res, ", ", witnames.ErrorType, ">")
+	}
+	g.P("}")
+	g.P()
+}
+
+// genStreamResource generates the resource representing a call of the
+// client-streaming or bidirectional streaming method m. The client
+// sends its messages to the resource and the server implementation is
+// invoked once the client closes its side of the stream.
+func (i *witInterface) genStreamResource(m *protogen.Method, g *protogen.GeneratedFile) {
+	g.P("  resource ", witnames.StreamResource(m.GoName), " {")
+	g.P("    constructor()")
+	g.P("    send: func(msg: ", i.names.MessageType(m.Input), ") -> result<_, ", witnames.ErrorType, ">")
+	if m.Desc.IsStreamingServer() {
+		g.P("    close-send: func() -> result<_, ", witnames.ErrorType, ">")
+		g.P("    recv: func() -> result<option<", i.names.MessageType(m.Output), ">, ", witnames.ErrorType, ">")
+	} else {
+		res := "_"
+		if !witnames.IsUnit(m.Output) {
+			res = i.names.MessageType(m.Output)
+		}
+		g.P("    close-and-recv: func() -> result<", res, ", ", witnames.ErrorType, ">")
+	}
+	g.P("  }")
+	g.P()
+}
+
+// genVariant generates a WIT variant with a case for each field of
+// oneof
+func (i *witInterface) genVariant(oneof *protogen.Oneof, g *protogen.GeneratedFile) {
+	g.P("  variant ", witnames.Ident(i.names.Name(oneof.GoIdent)), " {")
+	for _, f := range oneof.Fields {
+		typ, _ := i.fieldWitType(f)
+		g.P("    ", witnames.Ident(string(f.Desc.Name())), "(", typ, "),")
+	}
+	g.P("  }")
+	g.P()
+}
+
+// genValueNode generates the variant encoding the nodes of the types
+// of struct.proto
+func genValueNode(g *protogen.GeneratedFile) {
+	g.P("  variant ", witnames.ValueNode, " {")
+	for _, c := range witnames.ValueNodeCases {
+		if c.Type == "" {
+			g.P("    ", c.Name, ",")
+		} else {
+			g.P("    ", c.Name, "(", c.Type, "),")
+		}
+	}
+	g.P("  }")
+	g.P()
+}
+
+// fieldWitType returns the WIT type used to represent field. For
+// fields that are part of a oneof, the type of the variant case is
+// returned.
+func (i *witInterface) fieldWitType(field *protogen.Field) (string, error) {
+	if field.Desc.IsMap() {
+		key, err := i.fieldWitType(field.Message.Fields[0])
+		if err != nil {
+			return "", err
+		}
+		value, err := i.fieldWitType(field.Message.Fields[1])
+		if err != nil {
+			return "", err
+		}
+		return "list<tuple<" + key + ", " + value + ">>", nil
+	}
+	var typ string
+	if field.Message != nil {
+		if witnames.IsUnit(field.Message) {
+			return "", fmt.Errorf("field %s has type %s without fields which can only be used as a method argument or result",
+				field.Desc.FullName(), field.Message.Desc.FullName())
+		}
+		typ = i.names.MessageType(field.Message)
+	} else if field.Enum != nil {
+		typ = witnames.Ident(i.names.Name(field.Enum.GoIdent))
+	} else {
+		var err error
+		if typ, err = witnames.ScalarType(field.Desc.Kind()); err != nil {
+			return "", err
+		}
+	}
+	if field.Desc.IsList() {
+		typ = "list<" + typ + ">"
+	}
+	if witnames.IsOptional(field) {
+		typ = "option<" + typ + ">"
+	}
+	return typ, nil
+}
+
+func protocVersion(gen *protogen.Plugin) string {
+	v := gen.Request.GetCompilerVersion()
+	if v == nil {
+		return "(unknown)"
+	}
+	var suffix string
+	if s := v.GetSuffix(); s != "" {
+		suffix = "-" + s
+	}
+	return fmt.Sprintf("v%d.%d.%d%s", v.GetMajor(), v.GetMinor(), v.GetPatch(), suffix)
+}
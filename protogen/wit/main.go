@@ -0,0 +1,23 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/truls/cofaas-go/protogen/wit/genwit"
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+func main() {
+	showVersion := flag.Bool("version", false, "print the version and exit")
+	flag.Parse()
+	if *showVersion {
+		fmt.Printf("protoc-gen-cofaas-wit %v\n", genwit.Version)
+		return
+	}
+
+	p := genwit.New()
+	protogen.Options{
+		ParamFunc: p.Flags.Set,
+	}.Run(p.Run)
+}
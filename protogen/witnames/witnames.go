@@ -0,0 +1,453 @@
+// Package witnames derives WIT identifiers from protobuf names and
+// computes the Go identifiers that wit-bindgen generates for them.
+// Both the WIT generator and the component glue generator use it so
+// that the two always agree on naming.
+package witnames
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/truls/cofaas-go/protogen/types/internal_gengo/genid"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	// Namespace and Package form the WIT package cofaas:application
+	// that all generated interfaces belong to
+	Namespace = "cofaas"
+	Package   = "application"
+	// World is the name of the world generated for a component
+	World = "cofaas-component"
+	// ErrorType is the WIT type used for the error case of results
+	ErrorType = "s32"
+)
+
+// keywords that must be escaped with % when used as WIT identifiers
+var keywords = map[string]bool{
+	"use": true, "type": true, "func": true, "u8": true, "u16": true,
+	"u32": true, "u64": true, "s8": true, "s16": true, "s32": true,
+	"s64": true, "float32": true, "float64": true, "char": true,
+	"record": true, "resource": true, "own": true, "borrow": true,
+	"flags": true, "variant": true, "enum": true, "union": true,
+	"bool": true, "string": true, "option": true, "result": true,
+	"future": true, "stream": true, "list": true, "_": true, "as": true,
+	"from": true, "static": true, "interface": true, "tuple": true,
+	"import": true, "export": true, "world": true, "package": true,
+	"constructor": true, "include": true, "with": true,
+}
+
+// Kebab converts a protobuf or Go name such as HelloRequest,
+// Outer_Inner or user_id to a valid WIT identifier such as
+// hello-request, outer-inner or user-id. Words starting with a digit
+// are merged with the preceding word since WIT does not permit them.
+func Kebab(name string) string {
+	var words []string
+	var cur []rune
+	flush := func() {
+		if len(cur) > 0 {
+			words = append(words, strings.ToLower(string(cur)))
+			cur = nil
+		}
+	}
+	runes := []rune(name)
+	for i, r := range runes {
+		switch {
+		case r == '_' || r == '-' || r == '.':
+			flush()
+		case unicode.IsUpper(r):
+			// Start a new word at a lower-to-upper transition or at
+			// the last upper case letter of an acronym (HTTPRequest)
+			if len(cur) > 0 {
+				prev := runes[i-1]
+				nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+				if unicode.IsLower(prev) || unicode.IsDigit(prev) ||
+					(unicode.IsUpper(prev) && nextLower) {
+					flush()
+				}
+			}
+			cur = append(cur, r)
+		case unicode.IsDigit(r):
+			if len(cur) == 0 && len(words) > 0 {
+				// Merge into the preceding word
+				cur = []rune(words[len(words)-1])
+				words = words[:len(words)-1]
+			}
+			cur = append(cur, r)
+		default:
+			cur = append(cur, r)
+		}
+	}
+	flush()
+	if len(words) == 0 {
+		return ""
+	}
+	if unicode.IsDigit(rune(words[0][0])) {
+		words[0] = "x" + words[0]
+	}
+	return strings.Join(words, "-")
+}
+
+// Escape prefixes kebab-case identifiers that collide with WIT
+// keywords with %
+func Escape(ident string) string {
+	if keywords[ident] {
+		return "%" + ident
+	}
+	return ident
+}
+
+// Ident returns the escaped WIT identifier for name
+func Ident(name string) string {
+	return Escape(Kebab(name))
+}
+
+// GoName returns the Go identifier that wit-bindgen generates for the
+// WIT identifier ident
+func GoName(ident string) string {
+	ident = strings.TrimPrefix(ident, "%")
+	res := strings.Builder{}
+	for _, w := range strings.Split(ident, "-") {
+		if w == "" {
+			continue
+		}
+		res.WriteString(strings.ToUpper(w[:1]))
+		res.WriteString(w[1:])
+	}
+	return res.String()
+}
+
+// InterfaceGoName returns the prefix that wit-bindgen uses for all
+// identifiers generated for the WIT interface iface
+func InterfaceGoName(iface string) string {
+	return GoName(Namespace) + GoName(Package) + GoName(iface)
+}
+
+// TypeGoName returns the Go identifier of the WIT type typ defined in
+// the WIT interface iface
+func TypeGoName(iface string, typ string) string {
+	return InterfaceGoName(iface) + GoName(typ)
+}
+
+// EnumValues returns the values of enum that are represented as cases
+// of the corresponding WIT enum. Aliases, i.e., values with the same
+// number as a preceding value, are omitted.
+func EnumValues(enum *protogen.Enum) []*protogen.EnumValue {
+	var res []*protogen.EnumValue
+	for _, v := range enum.Values {
+		if enum.Desc.Values().ByNumber(v.Desc.Number()) == v.Desc {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// EnumCase returns the WIT enum case representing value
+func EnumCase(value *protogen.EnumValue) string {
+	return Ident(string(value.Desc.Name()))
+}
+
+// IsUnit reports whether msg has no fields. Such messages, e.g.,
+// google.protobuf.Empty, carry no information and are omitted from
+// the signatures of WIT functions.
+func IsUnit(msg *protogen.Message) bool {
+	return len(msg.Fields) == 0
+}
+
+// ValueNode is the WIT variant encoding a node of a
+// google.protobuf.Value, Struct or ListValue. As WIT types cannot be
+// recursive, these are represented by a list of the nodes of their
+// tree. The first node is the root and struct and list nodes refer to
+// their children by their index in the list. Children always follow
+// their parents.
+const ValueNode = "google-protobuf-value-node"
+
+// ValueNodeCases are the cases of ValueNode with the types of their
+// payloads. null-value has no payload.
+var ValueNodeCases = []struct{ Name, Type string }{
+	{"null-value", ""},
+	{"number-value", "float64"},
+	{"string-value", "string"},
+	{"bool-value", "bool"},
+	{"struct-value", "list<tuple<string, u32>>"},
+	{"list-value", "list<u32>"},
+}
+
+// IsValueTree reports whether msg is one of the recursive types of
+// struct.proto which are represented by lists of ValueNode
+func IsValueTree(msg *protogen.Message) bool {
+	switch msg.Desc.FullName() {
+	case genid.Struct_message_fullname,
+		genid.Value_message_fullname,
+		genid.ListValue_message_fullname:
+		return true
+	}
+	return false
+}
+
+// KnownType returns the WIT type used instead of a record for the
+// well-known type msg. Wrapper types are represented by the type of
+// their value and the types of struct.proto by a list of ValueNode.
+func KnownType(msg *protogen.Message) (string, bool) {
+	if IsValueTree(msg) {
+		return "list<" + ValueNode + ">", true
+	}
+	switch msg.Desc.FullName() {
+	case genid.BoolValue_message_fullname,
+		genid.Int32Value_message_fullname,
+		genid.Int64Value_message_fullname,
+		genid.UInt32Value_message_fullname,
+		genid.UInt64Value_message_fullname,
+		genid.FloatValue_message_fullname,
+		genid.DoubleValue_message_fullname,
+		genid.StringValue_message_fullname,
+		genid.BytesValue_message_fullname:
+		typ, err := ScalarType(msg.Fields[0].Desc.Kind())
+		return typ, err == nil
+	}
+	return "", false
+}
+
+// Names holds the names that the WIT types of the messages, enums and
+// oneofs used by a service are derived from. A type is named after its
+// Go identifier unless types of different protobuf packages share it,
+// such as acme.billing.v1.Money and acme.types.v1.Money. These are
+// qualified by their package, e.g., acme.billing.v1.Money which becomes
+// the WIT identifier acme-billing-v1-money.
+type Names struct {
+	// Protobuf packages of the qualified types
+	packages map[protogen.GoIdent]string
+}
+
+// NewNames returns the names of the types used by the methods of svc
+func NewNames(svc *protogen.Service) *Names {
+	packages := make(map[protogen.GoIdent]string)
+	byName := make(map[string][]protogen.GoIdent)
+	add := func(desc protoreflect.Descriptor, ident protogen.GoIdent) bool {
+		if _, ok := packages[ident]; ok {
+			return false
+		}
+		packages[ident] = string(desc.ParentFile().Package())
+		byName[ident.GoName] = append(byName[ident.GoName], ident)
+		return true
+	}
+	var addMessage func(msg *protogen.Message)
+	addMessage = func(msg *protogen.Message) {
+		if _, ok := KnownType(msg); ok || IsUnit(msg) || !add(msg.Desc, msg.GoIdent) {
+			return
+		}
+		for _, oneof := range msg.Oneofs {
+			if !oneof.Desc.IsSynthetic() {
+				add(oneof.Desc, oneof.GoIdent)
+			}
+		}
+		for _, f := range msg.Fields {
+			if f.Desc.IsMap() {
+				// Only the value of a map entry can refer to other types
+				f = f.Message.Fields[1]
+			}
+			if f.Message != nil {
+				addMessage(f.Message)
+			}
+			if f.Enum != nil {
+				add(f.Enum.Desc, f.Enum.GoIdent)
+			}
+		}
+	}
+	for _, m := range svc.Methods {
+		addMessage(m.Input)
+		addMessage(m.Output)
+	}
+
+	n := &Names{packages: make(map[protogen.GoIdent]string)}
+	for _, idents := range byName {
+		if len(idents) < 2 {
+			continue
+		}
+		for _, ident := range idents {
+			if pkg := packages[ident]; pkg != "" {
+				n.packages[ident] = pkg
+			}
+		}
+	}
+	return n
+}
+
+// Name returns the name that the WIT identifier of the message, enum
+// or oneof with the Go identifier ident is derived from
+func (n *Names) Name(ident protogen.GoIdent) string {
+	if pkg, ok := n.packages[ident]; ok {
+		return pkg + "." + ident.GoName
+	}
+	return ident.GoName
+}
+
+// GoName returns a Go identifier for the message, enum or oneof with
+// the Go identifier ident which is unique among the types of the
+// service
+func (n *Names) GoName(ident protogen.GoIdent) string {
+	if pkg, ok := n.packages[ident]; ok {
+		return GoName(Ident(pkg)) + ident.GoName
+	}
+	return ident.GoName
+}
+
+// MessageType returns the WIT type representing msg
+func (n *Names) MessageType(msg *protogen.Message) string {
+	if typ, ok := KnownType(msg); ok {
+		return typ
+	}
+	return Ident(n.Name(msg.GoIdent))
+}
+
+// IsOptional reports whether field is represented by a WIT option.
+// This is the case for singular fields with explicit presence, i.e.,
+// message fields and proto3 optional fields, except for members of a
+// oneof which are represented as cases of a variant and fields of map
+// entries which are always present.
+func IsOptional(field *protogen.Field) bool {
+	if field.Desc.IsList() || field.Desc.IsMap() || !field.Desc.HasPresence() {
+		return false
+	}
+	if field.Parent != nil && field.Parent.Desc.IsMapEntry() {
+		return false
+	}
+	return field.Oneof == nil || field.Oneof.Desc.IsSynthetic()
+}
+
+// ScalarType returns the WIT type used to represent values of the
+// scalar protobuf kind
+func ScalarType(kind protoreflect.Kind) (string, error) {
+	switch kind {
+	case protoreflect.BoolKind:
+		return "bool", nil
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return "s32", nil
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return "u32", nil
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return "s64", nil
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return "u64", nil
+	case protoreflect.FloatKind:
+		return "float32", nil
+	case protoreflect.DoubleKind:
+		return "float64", nil
+	case protoreflect.StringKind:
+		return "string", nil
+	case protoreflect.BytesKind:
+		return "list<u8>", nil
+	}
+	return "", fmt.Errorf("protobuf kind %s is not supported", kind)
+}
+
+// TupleGoName returns the Go identifier that wit-bindgen generates for
+// an anonymous tuple of the WIT types elems used in the WIT interface
+// iface, e.g., CofaasApplicationFooTuple2StringU32T for
+// tuple<string, u32>
+func TupleGoName(iface string, elems ...string) string {
+	res := strings.Builder{}
+	res.WriteString(InterfaceGoName(iface))
+	res.WriteString("Tuple")
+	res.WriteString(strconv.Itoa(len(elems)))
+	for _, e := range elems {
+		res.WriteString(anonTypeGoName(e))
+	}
+	res.WriteString("T")
+	return res.String()
+}
+
+// anonTypeGoName returns the name that wit-bindgen uses for typ when
+// it is part of the name of an anonymous type
+func anonTypeGoName(typ string) string {
+	if inner, ok := strings.CutPrefix(typ, "list<"); ok {
+		return "List" + anonTypeGoName(strings.TrimSuffix(inner, ">"))
+	}
+	return GoName(typ)
+}
+
+// StreamResource returns the WIT resource representing a call of the
+// client-streaming or bidirectional streaming method named method
+func StreamResource(method string) string {
+	return Escape(Kebab(method) + "-stream")
+}
+
+// ResourceExportGoName returns the Go interface that implementations
+// of the resource res exported by the WIT interface iface must satisfy
+func ResourceExportGoName(iface string, res string) string {
+	return "Exports" + TypeGoName(iface, res)
+}
+
+// ResourceConstructorGoName returns the name of the method creating
+// instances of the exported resource res
+func ResourceConstructorGoName(res string) string {
+	return "Constructor" + GoName(res)
+}
+
+// ResourceImportConstructorGoName returns the name of the function
+// creating instances of the imported resource res
+func ResourceImportConstructorGoName(res string) string {
+	return "New" + GoName(res)
+}
+
+// ResourceMethodGoName returns the name of the Go method implementing
+// method of the exported resource res
+func ResourceMethodGoName(res string, method string) string {
+	return "Method" + GoName(res) + GoName(method)
+}
+
+// ServiceList collects the values of the repeated services parameter
+// of the plugins which selects the exported services
+type ServiceList []string
+
+func (l *ServiceList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *ServiceList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// SelectServices returns the services of file that are named in names
+// or all services of file if names is empty
+func SelectServices(file *protogen.File, names []string) ([]*protogen.Service, error) {
+	if len(file.Services) == 0 {
+		return nil, fmt.Errorf("protocol %s does not define any services", file.Desc.Path())
+	}
+	if len(names) == 0 {
+		return file.Services, nil
+	}
+	var res []*protogen.Service
+	for _, name := range names {
+		var svc *protogen.Service
+		for _, s := range file.Services {
+			if string(s.Desc.Name()) == name {
+				svc = s
+			}
+		}
+		if svc == nil {
+			return nil, fmt.Errorf("protocol %s does not define the service %s", file.Desc.Path(), name)
+		}
+		res = append(res, svc)
+	}
+	return res, nil
+}
+
+// ExportImportFiles returns the files to generate of gen. The first
+// file is the export protocol and the remaining are import protocols.
+func ExportImportFiles(gen *protogen.Plugin) (*protogen.File, []*protogen.File, error) {
+	// gen.Files also contains the dependencies of the input files
+	files := gen.Request.FileToGenerate
+	if len(files) == 0 {
+		return nil, nil, fmt.Errorf("Specify one or more input files where the first file is the export protocol and the remaining files are the import protocols")
+	}
+	var importFiles []*protogen.File
+	for _, f := range files[1:] {
+		importFiles = append(importFiles, gen.FilesByPath[f])
+	}
+	return gen.FilesByPath[files[0]], importFiles, nil
+}
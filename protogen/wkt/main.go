@@ -0,0 +1,36 @@
+// The wkt command generates the cofaas versions of the protobuf
+// well-known types that are bundled in the stubs/protobuf module.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	c "github.com/truls/cofaas-go"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s <output dir>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	// The go frontend is used by default such that the generated code
+	// does not depend on the version of protoc
+	frontend := flag.String("frontend", string(c.GoFrontend), "The frontend parsing the protocol files. Either go or protoc")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if err := c.SetFrontend(c.Frontend(*frontend)); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if err := c.GenWellKnownTypes(flag.Arg(0)); err != nil {
+		fmt.Printf("Generating well-known types failed %s\n", c.FormatError(err))
+		os.Exit(1)
+	}
+}
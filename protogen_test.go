@@ -1,22 +1,226 @@
 package cofaas
 
 import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
 
-	opt "github.com/moznion/go-optional"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/descriptorpb"
 )
 
+// useGoFrontend makes the generators of the test use the go frontend
+// as the golden files must not depend on the version of protoc
+func useGoFrontend(t *testing.T) {
+	old := frontend
+	frontend = GoFrontend
+	t.Cleanup(func() { frontend = old })
+}
+
 func TestGenGrpcCode(t *testing.T) {
+	useGoFrontend(t)
 	compareGoldenFile(t, "helloworld.proto", nil, call1test(GenGrpcCode), *update, *verbose)
+	compareGoldenFile(t, "stream.proto", nil, call1test(GenGrpcCode), *update, *verbose)
+	compareGoldenFile(t, "imports.proto", nil, call1test(GenGrpcCode, "include"), *update, *verbose)
 	//compareGoldenFile(t, "prodcon.proto", GenGrpcCode, *update, *verbose)
 }
 
 func TestGenProtoCode(t *testing.T) {
+	useGoFrontend(t)
 	compareGoldenFile(t, "helloworld_protogen.proto", nil, call1test(GenProtoCode), *update, *verbose)
 	compareGoldenFile(t, "prodcon_protogen.proto", nil, call1test(GenProtoCode), *update, *verbose)
+	compareGoldenFileAs(t, "imports.proto", "imports.proto.protogen", nil, call1test(GenProtoCode, "include"), *update, *verbose)
 }
 
 func TestGenComponentCode(t *testing.T) {
-	compareGoldenFile(t, "helloworld_component.proto", opt.Some("prodcon.proto"), GenComponentCode, *update, *verbose)
+	useGoFrontend(t)
+	compareGoldenFile(t, "helloworld_component.proto", []string{"prodcon.proto"}, call2test(GenComponentCode), *update, *verbose)
+	compareGoldenFile(t, "nested_component.proto", []string{"prodcon.proto"}, call2test(GenComponentCode), *update, *verbose)
+	compareGoldenFileAs(t, "enum.proto", "enum.proto.component", nil, call2test(GenComponentCode), *update, *verbose)
+	compareGoldenFileAs(t, "oneof.proto", "oneof.proto.component", nil, call2test(GenComponentCode), *update, *verbose)
+	compareGoldenFileAs(t, "map.proto", "map.proto.component", nil, call2test(GenComponentCode), *update, *verbose)
+	compareGoldenFileAs(t, "optional.proto", "optional.proto.component", nil, call2test(GenComponentCode), *update, *verbose)
+	compareGoldenFileAs(t, "wkt.proto", "wkt.proto.component", []string{"clock.proto"}, call2test(GenComponentCode), *update, *verbose)
+	compareGoldenFileAs(t, "stream.proto", "stream.proto.component", []string{"ticker.proto"}, call2test(GenComponentCode), *update, *verbose)
+	compareGoldenFileAs(t, "multi.proto", "multi.proto.component", []string{"prodcon.proto", "clock.proto", "ticker.proto"}, call2test(GenComponentCode), *update, *verbose)
+	compareGoldenFileAs(t, "shop.proto", "shop.proto.component", []string{"catalog.proto"}, call2test(GenComponentCode), *update, *verbose)
+	compareGoldenFileAs(t, "dotted.proto", "dotted.proto.component", []string{"users.proto"}, call2test(GenComponentCode), *update, *verbose)
+	compareGoldenFileAs(t, "imports.proto", "imports.proto.component", []string{"users.proto"}, call2testIncludes(GenComponentCode, []string{"include"}), *update, *verbose)
+	compareGoldenFileAs(t, "clash.proto", "clash.proto.component", nil, call2testIncludes(GenComponentCode, []string{"include"}), *update, *verbose)
 	//compareGoldenFile(t, "prodcon.proto", GenGrpcCode, *update, *verbose)
 }
+
+func TestGenWitCode(t *testing.T) {
+	useGoFrontend(t)
+	compareGoldenFileAs(t, "helloworld.proto", "helloworld.proto.wit", []string{"prodcon.proto"}, call2test(GenWitCode), *update, *verbose)
+	compareGoldenFileAs(t, "enum.proto", "enum.proto.wit", nil, call2test(GenWitCode), *update, *verbose)
+	compareGoldenFileAs(t, "oneof.proto", "oneof.proto.wit", nil, call2test(GenWitCode), *update, *verbose)
+	compareGoldenFileAs(t, "map.proto", "map.proto.wit", nil, call2test(GenWitCode), *update, *verbose)
+	compareGoldenFileAs(t, "optional.proto", "optional.proto.wit", nil, call2test(GenWitCode), *update, *verbose)
+	compareGoldenFileAs(t, "wkt.proto", "wkt.proto.wit", []string{"clock.proto"}, call2test(GenWitCode), *update, *verbose)
+	compareGoldenFileAs(t, "stream.proto", "stream.proto.wit", []string{"ticker.proto"}, call2test(GenWitCode), *update, *verbose)
+	compareGoldenFileAs(t, "multi.proto", "multi.proto.wit", []string{"prodcon.proto", "clock.proto", "ticker.proto"}, call2test(GenWitCode), *update, *verbose)
+	compareGoldenFileAs(t, "shop.proto", "shop.proto.wit", []string{"catalog.proto"}, call2test(GenWitCode, "ShopAdmin"), *update, *verbose)
+	compareGoldenFileAs(t, "dotted.proto", "dotted.proto.wit", []string{"users.proto"}, call2test(GenWitCode), *update, *verbose)
+	compareGoldenFileAs(t, "imports.proto", "imports.proto.wit", []string{"users.proto"}, call2testIncludes(GenWitCode, []string{"include"}), *update, *verbose)
+	compareGoldenFileAs(t, "clash.proto", "clash.proto.wit", nil, call2testIncludes(GenWitCode, []string{"include"}), *update, *verbose)
+}
+
+func TestFrontendsAgree(t *testing.T) {
+	if _, err := exec.LookPath("protoc"); err != nil {
+		t.Skip("protoc is not installed")
+	}
+	for _, tc := range []struct {
+		file     string
+		others   []string
+		includes []string
+	}{
+		{"helloworld.proto", nil, nil},
+		{"wkt.proto", []string{"clock.proto"}, nil},
+		{"imports.proto", nil, []string{"include"}},
+	} {
+		var res [2][]*descriptorpb.FileDescriptorProto
+		for i, f := range []Frontend{ProtocFrontend, GoFrontend} {
+			g, err := newGenerator(getTestInput(tc.file), getTestInputs(tc.others), getTestInputs(tc.includes))
+			if err != nil {
+				t.Fatal(err)
+			}
+			g.frontend = f
+			res[i], err = g.descriptors()
+			g.cleanup()
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if diff := cmp.Diff(res[0], res[1], protocmp.Transform()); diff != "" {
+			t.Errorf("protoc and go frontends disagree on %s\n%s", tc.file, diff)
+		}
+	}
+}
+
+// writeDescriptorSet compiles the test inputs files into a
+// FileDescriptorSet and returns its path
+func writeDescriptorSet(t *testing.T, files []string) string {
+	descs, err := parseFiles([]string{"testdata", "testdata/include"}, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := proto.Marshal(&descriptorpb.FileDescriptorSet{File: descs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := filepath.Join(t.TempDir(), "image.binpb")
+	if err := os.WriteFile(set, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return set
+}
+
+// TestDescriptorSetInput checks that protocols given by a descriptor
+// set generate the same code as their .proto files
+func TestDescriptorSetInput(t *testing.T) {
+	useGoFrontend(t)
+	for _, tc := range []struct {
+		file   string
+		golden string
+		others []string
+		gen    func(file string, others []string, includes []string) (string, error)
+	}{
+		{"imports.proto", "imports.proto", nil, func(file string, _ []string, includes []string) (string, error) {
+			return GenGrpcCode(file, includes)
+		}},
+		{"imports.proto", "imports.proto.protogen", nil, func(file string, _ []string, includes []string) (string, error) {
+			return GenProtoCode(file, includes)
+		}},
+		{"imports.proto", "imports.proto.component", []string{"users.proto"}, func(file string, others []string, includes []string) (string, error) {
+			return GenComponentCode(file, others, includes)
+		}},
+		{"imports.proto", "imports.proto.wit", []string{"users.proto"}, func(file string, others []string, includes []string) (string, error) {
+			return GenWitCode(file, others, includes)
+		}},
+	} {
+		set := writeDescriptorSet(t, append([]string{tc.file}, tc.others...))
+		expected, err := readGoldenFile(tc.golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual, err := tc.gen(tc.file, tc.others, []string{set})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual != expected {
+			t.Errorf("code generated for %s from a descriptor set differs from the golden file", tc.golden)
+		}
+	}
+
+	set := writeDescriptorSet(t, []string{"imports.proto", "users.proto"})
+	includes := []string{set}
+	deps, err := ProtoDependencies("imports.proto", includes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, d := range deps {
+		if d.Path != "" {
+			t.Errorf("expected no path for %s defined in a descriptor set", d.Name)
+		}
+		names = append(names, d.Name)
+	}
+	if diff := cmp.Diff([]string{"acme/types/v1/money.proto", "acme/billing/v1/invoice.proto"}, names); diff != "" {
+		t.Errorf("unexpected dependencies\n%s", diff)
+	}
+
+	name, err := DescriptorSetFile(set, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "users.proto" {
+		t.Errorf("expected users.proto to be the last file of the set but got %s", name)
+	}
+}
+
+// TestGenWellKnownTypes checks that the bundled well-known type
+// packages are up to date
+func TestGenWellKnownTypes(t *testing.T) {
+	useGoFrontend(t)
+	dir := t.TempDir()
+	if err := GenWellKnownTypes(dir); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range sortedWellKnownTypes() {
+		name := filepath.Join(wellKnownTypes[f], strings.TrimSuffix(filepath.Base(f), ".proto")+".pb.go")
+		actual, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, err := os.ReadFile(filepath.Join("stubs/protobuf/types/known", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(actual) != string(expected) {
+			t.Errorf("%s is out of date. Run go generate to update it", name)
+		}
+	}
+}
+
+// TestGoImportPath checks that the Go import paths are derived from
+// the go_package options of the protocol files
+func TestGoImportPath(t *testing.T) {
+	useGoFrontend(t)
+	for file, expected := range map[string]string{
+		"testdata/helloworld.proto": "github.com/truls/chained-service-example/helloworld",
+		"testdata/users.proto":      "github.com/acme/users/v1",
+	} {
+		actual, err := GoImportPath(file, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual != expected {
+			t.Errorf("expected import path %s for %s but got %s", expected, file, actual)
+		}
+	}
+}
@@ -1,9 +1,10 @@
 package cofaas
 
 import (
-	"fmt"
+	"go/token"
 	"os"
 	"path"
+	"path/filepath"
 	"strings"
 
 	"github.com/go-errors/errors"
@@ -22,8 +23,19 @@ const (
 
 type PkgRewriter struct {
 	Metadata *metadata.Metadata
-	pkg      *pkg.Package
-	ModDir   string
+	// The packages of the module. The first package is the main
+	// package in the root of the module
+	pkgs   []*pkg.Package
+	ModDir string
+	fset   *token.FileSet
+	// What the type checker resolved in the files of the packages
+	// indexed by their paths
+	files map[string]*fileInfo
+	// Module path of the implementation before it is renamed
+	origModule string
+	// Import path of the implementation package if it was extracted
+	// from a larger module
+	origPkgPath string
 }
 
 type PkgSpec struct {
@@ -46,25 +58,46 @@ func (s PkgSpec) Format() string {
 
 type PkgReplacement map[string]*PkgSpec
 
-// NewPkgRewriter copies the package found in modPath to baseDir,
-// renames the package according to the cofaas module hierarchy and
-// finally loads and parses the package. If this is successful, a
+// NewPkgRewriter copies the module found in modPath to baseDir, loads
+// and parses its packages and finally renames the module according to
+// the cofaas module hierarchy. If modPath is a package inside a larger
+// module, the package and the packages of the module it imports are
+// extracted into a module of their own. If this is successful, a
 // PkgRewriter object is returned or otherwise an error
 func NewPackageRewriter(modPath string, baseDir string) (*PkgRewriter, error) {
 	implPath := path.Join(baseDir, "impl")
 
-	if err := cp.Copy(modPath, implPath); err != nil {
+	p := PkgRewriter{ModDir: implPath}
+	if _, err := os.Stat(path.Join(modPath, "go.mod")); err == nil {
+		if err := cp.Copy(modPath, implPath); err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+	} else if os.IsNotExist(err) {
+		if p.origPkgPath, err = extractPackage(modPath, implPath); err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+	} else {
 		return nil, errors.Wrap(err, 0)
 	}
-
-	p := PkgRewriter{ModDir: implPath}
-	if err := p.renameModule(); err != nil {
+	if err := p.loadMetadata(modPath); err != nil {
 		return nil, errors.Wrap(err, 0)
 	}
-	if err := p.loadPackage(); err != nil {
+	modFile, err := findUp(modPath, "go.mod")
+	if err != nil {
 		return nil, errors.Wrap(err, 0)
 	}
-	if err := p.loadMetadata(modPath); err != nil {
+	if err := p.fixReplacements(filepath.Dir(modFile)); err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	if err := p.useWorkspace(modPath); err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	// The packages are loaded before the module is renamed as the
+	// imports between them refer to the original module path
+	if err := p.loadPackages(); err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	if err := p.renameModule(); err != nil {
 		return nil, errors.Wrap(err, 0)
 	}
 	return &p, nil
@@ -74,11 +107,61 @@ func NewPackageRewriter(modPath string, baseDir string) (*PkgRewriter, error) {
 func (p *PkgRewriter) renameModule() error {
 	return p.transformMod(
 		func(f *modfile.File) error {
+			p.origModule = f.Module.Mod.Path
 			f.AddModuleStmt(implPkgPath)
 			return nil
 		})
 }
 
+// fixReplacements makes the directory replacements of the copied
+// go.mod, which are relative to the directory origDir of the original
+// go.mod, absolute. The replacements and requirements of the protocol
+// packages of the metadata are dropped as they are replaced with the
+// generated protocol modules. An error is returned if a replacement
+// directory does not contain a module.
+func (p *PkgRewriter) fixReplacements(origDir string) error {
+	protos := map[string]bool{p.Metadata.ExportProto.Import: true}
+	for _, s := range p.Metadata.ImportProtos {
+		protos[s.Import] = true
+	}
+
+	return p.transformMod(func(f *modfile.File) error {
+		// The replacements and requirements are modified while
+		// iterating over them
+		for _, r := range append([]*modfile.Replace{}, f.Replace...) {
+			old := r.Old
+			if protos[old.Path] {
+				if err := f.DropReplace(old.Path, old.Version); err != nil {
+					return errors.Wrap(err, 0)
+				}
+				continue
+			}
+			// Replacements by directories have no version
+			if r.New.Version != "" {
+				continue
+			}
+			dir := r.New.Path
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(origDir, dir)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "go.mod")); err != nil {
+				return errors.Errorf("replacement %s => %s of %s does not resolve to a module: %v", old.Path, r.New.Path, path.Join(origDir, "go.mod"), err)
+			}
+			if err := f.AddReplace(old.Path, old.Version, dir, ""); err != nil {
+				return errors.Wrap(err, 0)
+			}
+		}
+		for _, r := range append([]*modfile.Require{}, f.Require...) {
+			if protos[r.Mod.Path] {
+				if err := f.DropRequire(r.Mod.Path); err != nil {
+					return errors.Wrap(err, 0)
+				}
+			}
+		}
+		return nil
+	})
+}
+
 func (p *PkgRewriter) transformMod(tf func(*modfile.File) error) error {
 	modPath := path.Join(p.ModDir, "go.mod")
 	c, err := os.ReadFile(modPath)
@@ -96,15 +179,24 @@ func (p *PkgRewriter) transformMod(tf func(*modfile.File) error) error {
 	if err != nil {
 		return errors.Wrap(err, 0)
 	}
-	fmt.Printf("%s", string(newMod))
 	if err := os.WriteFile(modPath, newMod, 0644); err != nil {
 		return errors.Wrap(err, 0)
 	}
 	return nil
 }
 
-func (pr *PkgRewriter) loadMetadata(implPath string) error {
+// LoadMetadata parses the protocol metadata of the implementation in
+// implPath
+func LoadMetadata(implPath string) (*metadata.Metadata, error) {
 	m, err := metadata.Parse(path.Join(implPath, metadataFile), true)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	return m, nil
+}
+
+func (pr *PkgRewriter) loadMetadata(implPath string) error {
+	m, err := LoadMetadata(implPath)
 	if err != nil {
 		return errors.Wrap(err, 0)
 	}
@@ -113,48 +205,127 @@ func (pr *PkgRewriter) loadMetadata(implPath string) error {
 	return nil
 }
 
-func (pr *PkgRewriter) loadPackage() error {
-	cfg := pkg.Config{
-		Mode: pkg.NeedName | pkg.NeedFiles,
-		Dir:  pr.ModDir}
-
-	pkgs, err := pkg.Load(&cfg, implPkgPath)
+func (pr *PkgRewriter) loadPackages() error {
+	data, err := os.ReadFile(path.Join(pr.ModDir, "go.mod"))
 	if err != nil {
 		return errors.Wrap(err, 0)
 	}
+	modulePath := modfile.ModulePath(data)
 
-	if len(pkgs) != 1 {
-		return errors.Errorf("input module must contain only a single package")
-	}
+	// The dependencies are type checked from source rather than
+	// export data whose format depends on the go toolchain
+	pr.fset = token.NewFileSet()
+	cfg := pkg.Config{
+		Mode: pkg.NeedName | pkg.NeedFiles | pkg.NeedImports | pkg.NeedDeps | pkg.NeedTypes | pkg.NeedSyntax | pkg.NeedTypesInfo,
+		Dir:  pr.ModDir,
+		Fset: pr.fset}
 
-	p := *pkgs[0]
+	pkgs, err := pkg.Load(&cfg, "./...")
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
 
-	if len(p.Errors) > 0 {
-		errs := strings.Builder{}
-		errs.WriteString("Loading package failed\n")
+	// Imports that cannot be resolved, such as the protocol packages
+	// that are replaced with the generated modules, are expected. The
+	// type information is incomplete for the code using them.
+	errs := strings.Builder{}
+	for _, p := range pkgs {
 		for _, e := range p.Errors {
+			if e.Kind != pkg.ParseError {
+				continue
+			}
 			errs.WriteString(e.Error())
 			errs.WriteString("\n")
 		}
-		return errors.Errorf("%s", errs.String())
+	}
+	if errs.Len() > 0 {
+		return errors.Errorf("Loading package failed\n%s", errs.String())
 	}
 
-	if p.Name != "main" {
-		return errors.Errorf("package must be named main not %s", p.Name)
+	var root *pkg.Package
+	for _, p := range pkgs {
+		if p.PkgPath == modulePath {
+			root = p
+		} else {
+			pr.pkgs = append(pr.pkgs, p)
+		}
+	}
+	if root == nil {
+		return errors.Errorf("module %s must contain a package in its root directory", modulePath)
+	}
+	if root.Name != "main" {
+		return errors.Errorf("package must be named main not %s", root.Name)
 	}
-	pr.pkg = &p
+	pr.pkgs = append([]*pkg.Package{root}, pr.pkgs...)
+	pr.analyze()
 
 	return nil
 }
 
-func (r *PkgRewriter) Rewrite(protoReplaements PkgReplacement) error {
-	for _, n := range r.pkg.GoFiles {
-		rewritten, err := NewSrcRewriter(protoReplaements).Rewrite(n)
-		if err != nil {
-			return errors.Wrap(err, 0)
+// rewriter returns the source rewriter of the i'th package of the
+// module
+func (r *PkgRewriter) rewriter(i int, protoReplacements PkgReplacement) Rewriter {
+	// The imports of the packages of the extracted package are renamed
+	// before the imports of the other packages of its module
+	var renames []importRename
+	if r.origPkgPath != "" {
+		renames = append(renames, importRename{from: r.origPkgPath, to: implPkgPath})
+	}
+	renames = append(renames, importRename{from: r.origModule, to: implPkgPath})
+	return &srcRewriter{
+		protoImportReplacements: protoReplacements,
+		subPkg:                  i > 0,
+		renames:                 renames,
+		files:                   r.files,
+	}
+}
+
+// Diff returns unified diffs of the changes Rewrite makes to the files
+// of the packages. The files are named relative to baseDir.
+func (r *PkgRewriter) Diff(protoReplacements PkgReplacement, baseDir string) ([]string, error) {
+	if err := r.checkStubs(protoReplacements); err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	var res []string
+	for i, p := range r.pkgs {
+		for _, n := range p.GoFiles {
+			rewritten, err := r.rewriter(i, protoReplacements).Rewrite(n)
+			if err != nil {
+				return nil, errors.Wrap(err, 0)
+			}
+			after, err := rewritten.Format()
+			if err != nil {
+				return nil, errors.Wrap(err, 0)
+			}
+			before, err := os.ReadFile(n)
+			if err != nil {
+				return nil, errors.Wrap(err, 0)
+			}
+			name, err := filepath.Rel(baseDir, n)
+			if err != nil {
+				return nil, errors.Wrap(err, 0)
+			}
+			if diff := UnifiedDiff(filepath.ToSlash(name), string(before), after); diff != "" {
+				res = append(res, diff)
+			}
 		}
-		if err := rewritten.Write(n); err != nil {
-			return errors.Wrap(err, 0)
+	}
+	return res, nil
+}
+
+func (r *PkgRewriter) Rewrite(protoReplaements PkgReplacement) error {
+	if err := r.checkStubs(protoReplaements); err != nil {
+		return errors.Wrap(err, 0)
+	}
+	for i, p := range r.pkgs {
+		for _, n := range p.GoFiles {
+			rewritten, err := r.rewriter(i, protoReplaements).Rewrite(n)
+			if err != nil {
+				return errors.Wrap(err, 0)
+			}
+			if err := rewritten.Write(n); err != nil {
+				return errors.Wrap(err, 0)
+			}
 		}
 	}
 
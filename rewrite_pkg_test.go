@@ -0,0 +1,167 @@
+package cofaas
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/mod/modfile"
+)
+
+// rewritePkgDiff returns the files of the implementation module created
+// from the module or package input followed by the diffs of its
+// rewrite
+func rewritePkgDiff(t *testing.T, input string) (string, error) {
+	replacements := PkgReplacement{
+		"net":                            {Name: "github.com/truls/cofaas-go/stubs/net"},
+		"example.com/typed/protos/hello": {Name: "cofaas/proto/example.com/hello/v1"},
+	}
+
+	dir := t.TempDir()
+	r, err := NewPackageRewriter(getTestInput(input), dir)
+	if err != nil {
+		return "", err
+	}
+
+	var res strings.Builder
+	err = filepath.WalkDir(r.ModDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		res.WriteString(filepath.ToSlash(rel) + "\n")
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	diffs, err := r.Diff(replacements, dir)
+	if err != nil {
+		return "", err
+	}
+	res.WriteString(strings.Join(diffs, ""))
+	return res.String(), nil
+}
+
+func TestRewriteMultiPackage(t *testing.T) {
+	compareGoldenFile(t, "multipkg.diff", nil, func(string, []string) (string, error) {
+		return rewritePkgDiff(t, "multipkg")
+	}, *update, *verbose)
+}
+
+func TestRewriteExtractedPackage(t *testing.T) {
+	compareGoldenFile(t, "monorepo.diff", nil, func(string, []string) (string, error) {
+		return rewritePkgDiff(t, "monorepo/fns/greeter")
+	}, *update, *verbose)
+}
+
+func TestRewriteTyped(t *testing.T) {
+	compareGoldenFile(t, "typed.diff", nil, func(string, []string) (string, error) {
+		return rewritePkgDiff(t, "typed")
+	}, *update, *verbose)
+}
+
+func TestUnsupported(t *testing.T) {
+	r, err := NewPackageRewriter(getTestInput("unsupported"), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := r.Unsupported(PkgReplacement{
+		"net": {Name: "github.com/truls/cofaas-go/stubs/net"},
+	})
+	expected := []string{
+		"impl/main.go:10:15: net.Dial is not implemented by github.com/truls/cofaas-go/stubs/net",
+		"impl/main.go:14:13: net.Conn.Close is not implemented by github.com/truls/cofaas-go/stubs/net",
+		"impl/main.go:15:15: net.ResolveTCPAddr is not implemented by github.com/truls/cofaas-go/stubs/net",
+		"impl/main.go:17:36: net.Addr.Network is not implemented by github.com/truls/cofaas-go/stubs/net",
+	}
+	if diff := cmp.Diff(expected, res); diff != "" {
+		t.Errorf("unexpected unsupported uses (-want +got):\n%s", diff)
+	}
+}
+
+func TestRewriteWorkspace(t *testing.T) {
+	r, err := NewPackageRewriter(getTestInput("workspace/fn"), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(r.ModDir, "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := modfile.Parse("go.mod", data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared, err := filepath.Abs(getTestInput("workspace/shared"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Replace) != 1 || f.Replace[0].Old.Path != "example.com/shared" || f.Replace[0].New.Path != shared {
+		t.Errorf("expected example.com/shared to be replaced with %s, got %v", shared, f.Replace)
+	}
+}
+
+func TestFixReplacements(t *testing.T) {
+	r, err := NewPackageRewriter(getTestInput("replace/fn"), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(r.ModDir, "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := modfile.Parse("go.mod", data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lib, err := filepath.Abs(getTestInput("replace/lib"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replacements := make(map[string]string)
+	for _, r := range f.Replace {
+		replacements[r.Old.Path] = r.New.String()
+	}
+	expected := map[string]string{
+		"example.com/lib":  lib,
+		"golang.org/x/net": "golang.org/x/net@v0.17.0",
+	}
+	if diff := cmp.Diff(expected, replacements); diff != "" {
+		t.Errorf("unexpected replacements (-want +got):\n%s", diff)
+	}
+	for _, r := range f.Require {
+		if r.Mod.Path == "example.com/greeter/protos/helloworld" {
+			t.Errorf("the requirement of the protocol package was not dropped")
+		}
+	}
+}
+
+func TestFixReplacementsUnresolved(t *testing.T) {
+	dir := t.TempDir()
+	mod := "module example.com/fn\n\ngo 1.20\n\nreplace example.com/lib => ../missing\n"
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte(mod), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	proto, err := filepath.Abs(getTestInput("helloworld.proto"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta := "proto-map:\n  - import: example.com/fn/protos\n    name: helloworld\n    path: " + proto + "\n    role: export\n"
+	if err := os.WriteFile(filepath.Join(dir, metadataFile), []byte(meta), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewPackageRewriter(dir, t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "does not resolve to a module") {
+		t.Errorf("expected the unresolved replacement to be reported, got %v", err)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"go/printer"
 	"go/token"
 	"os"
+	"path"
 	"strings"
 
 	"github.com/go-errors/errors"
@@ -26,6 +27,26 @@ var extraPackages = []string{
 type srcRewriter struct {
 	Rewriter
 	protoImportReplacements PkgReplacement
+	// The file is part of a package of the module other than its main
+	// package
+	subPkg bool
+	// Renames of the imports of the packages of the implementation
+	// module. The first matching rename is applied
+	renames []importRename
+	// What the type checker resolved in the files indexed by their
+	// paths. The rewrite is based on the names of the declarations
+	// for files that were not type checked.
+	files map[string]*fileInfo
+	// The file being rewritten
+	fset *token.FileSet
+	info *fileInfo
+}
+
+// importRename renames the imports of the package from and its
+// subpackages to the package to and its subpackages
+type importRename struct {
+	from string
+	to   string
 }
 
 type srcRewritten struct {
@@ -47,10 +68,15 @@ func (r *srcRewriter) applyFunction(c *astutil.Cursor) bool {
 	case *ast.FuncDecl:
 		id := x.Name
 		// Export main function
-		if id.Name == "main" {
+		if r.info == nil && id.Name == "main" && !r.subPkg {
 			x.Name.Name = "Main"
 			c.Replace(x)
 		}
+	case *ast.Ident:
+		// Export main function and rename its uses
+		if r.info != nil && r.info.mainRefs[r.fset.Position(x.Pos()).Offset] {
+			x.Name = "Main"
+		}
 	case *ast.ImportSpec:
 		im := x.Path.Value
 		//if strings.Contains(im, "google.golang.org/grpc") || im == "\"net\"" {
@@ -61,8 +87,21 @@ func (r *srcRewriter) applyFunction(c *astutil.Cursor) bool {
 		lookupPath := strings.Trim(im, "\"")
 		if v, ok := r.protoImportReplacements[lookupPath]; ok {
 			x.Path.Value = fmt.Sprintf("\"%s\"", v.Name)
+			// The file refers to the package by the name of the
+			// replaced package which may differ from the last element
+			// of the import path of the replacement
+			if name, ok := r.info.importName(lookupPath); ok && x.Name == nil && name != path.Base(v.Name) {
+				x.Name = ast.NewIdent(name)
+			}
 			c.Replace(x)
-			delete(r.protoImportReplacements, lookupPath)
+		} else {
+			for _, rn := range r.renames {
+				if strings.HasPrefix(lookupPath, rn.from+"/") {
+					x.Path.Value = fmt.Sprintf("\"%s\"", rn.to+strings.TrimPrefix(lookupPath, rn.from))
+					c.Replace(x)
+					break
+				}
+			}
 		}
 		// }
 	}
@@ -70,6 +109,16 @@ func (r *srcRewriter) applyFunction(c *astutil.Cursor) bool {
 	return true
 }
 
+// importName returns the name of the package imported without a name
+// by importPath if it was resolved
+func (i *fileInfo) importName(importPath string) (string, bool) {
+	if i == nil {
+		return "", false
+	}
+	name, ok := i.importNames[importPath]
+	return name, ok
+}
+
 func (r *srcRewriter) Rewrite(file string) (Rewritten, error) {
 	fset := token.NewFileSet()
 	f, err := parser.ParseFile(fset, file, nil, parser.AllErrors)
@@ -77,8 +126,12 @@ func (r *srcRewriter) Rewrite(file string) (Rewritten, error) {
 		return nil, errors.Wrap(err, 0)
 	}
 
-	f.Name.Name = "impl"
+	if !r.subPkg {
+		f.Name.Name = "impl"
+	}
 
+	r.fset = fset
+	r.info = r.files[file]
 	astutil.Apply(f, nil, r.applyFunction)
 
 	// Add import of stub libraries to file
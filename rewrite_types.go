@@ -0,0 +1,169 @@
+package cofaas
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-errors/errors"
+)
+
+// fileInfo is what the type checker resolved in a file of the
+// implementation that the rewrite depends on
+type fileInfo struct {
+	// Offsets of the identifiers referring to the main function of
+	// the main package
+	mainRefs map[int]bool
+	// Names of the packages imported without a name indexed by their
+	// import paths. Only packages that were resolved are included.
+	importNames map[string]string
+}
+
+// analyze collects the fileInfo of the files of the packages
+func (r *PkgRewriter) analyze() {
+	r.files = make(map[string]*fileInfo)
+	for i, p := range r.pkgs {
+		if p.Types == nil || p.TypesInfo == nil {
+			continue
+		}
+		var mainFn types.Object
+		if i == 0 {
+			if fn, ok := p.Types.Scope().Lookup("main").(*types.Func); ok {
+				mainFn = fn
+			}
+		}
+
+		for _, f := range p.Syntax {
+			info := &fileInfo{mainRefs: make(map[int]bool), importNames: make(map[string]string)}
+			r.files[r.fset.Position(f.Pos()).Filename] = info
+
+			for _, spec := range f.Imports {
+				if spec.Name != nil {
+					continue
+				}
+				pn, ok := p.TypesInfo.Implicits[spec].(*types.PkgName)
+				if !ok || !pn.Imported().Complete() {
+					continue
+				}
+				if im, err := strconv.Unquote(spec.Path.Value); err == nil {
+					info.importNames[im] = pn.Imported().Name()
+				}
+			}
+
+			if mainFn == nil {
+				continue
+			}
+			ast.Inspect(f, func(n ast.Node) bool {
+				id, ok := n.(*ast.Ident)
+				if !ok {
+					return true
+				}
+				if p.TypesInfo.Defs[id] == mainFn || p.TypesInfo.Uses[id] == mainFn {
+					info.mainRefs[r.fset.Position(id.Pos()).Offset] = true
+				}
+				return true
+			})
+		}
+	}
+}
+
+// Unsupported returns the uses of the declarations of packages that
+// are replaced with stubs by replacements which the stubs do not
+// implement. Each use is prefixed by its position relative to the
+// parent of the module directory. Dot imports and named imports are
+// resolved by the type checker. Uses whose types could not be resolved
+// are not reported.
+func (r *PkgRewriter) Unsupported(replacements PkgReplacement) []string {
+	type use struct {
+		pos token.Position
+		msg string
+	}
+	var uses []use
+	for _, p := range r.pkgs {
+		if p.TypesInfo == nil {
+			continue
+		}
+		for id, obj := range p.TypesInfo.Uses {
+			if obj.Pkg() == nil {
+				continue
+			}
+			spec, ok := replacements[obj.Pkg().Path()]
+			if !ok {
+				continue
+			}
+			if _, ok := stubAPI[spec.Name]; !ok {
+				continue
+			}
+
+			var name string
+			switch o := obj.(type) {
+			case *types.PkgName:
+				continue
+			case *types.Func:
+				if recv := o.Type().(*types.Signature).Recv(); recv != nil {
+					typeName := recvTypeName(recv.Type())
+					if stubImplements(spec.Name, stubMethod(obj.Pkg().Path(), typeName, o.Name())) {
+						continue
+					}
+					name = typeName + "." + o.Name()
+					break
+				}
+				if stubImplements(spec.Name, o.Name()) {
+					continue
+				}
+				name = o.Name()
+			default:
+				// Fields and other local declarations are not part of
+				// the API of the stubs
+				if obj.Parent() != obj.Pkg().Scope() || stubImplements(spec.Name, obj.Name()) {
+					continue
+				}
+				name = obj.Name()
+			}
+
+			pos := r.fset.Position(id.Pos())
+			if rel, err := filepath.Rel(filepath.Dir(r.ModDir), pos.Filename); err == nil {
+				pos.Filename = filepath.ToSlash(rel)
+			}
+			uses = append(uses, use{pos, fmt.Sprintf("%s: %s.%s is not implemented by %s", pos, obj.Pkg().Name(), name, spec.Name)})
+		}
+	}
+
+	sort.Slice(uses, func(i, j int) bool {
+		a, b := uses[i].pos, uses[j].pos
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		return a.Offset < b.Offset
+	})
+	var res []string
+	for _, u := range uses {
+		res = append(res, u.msg)
+	}
+	return res
+}
+
+// recvTypeName returns the name of the type of the receiver t
+func recvTypeName(t types.Type) string {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	if named, ok := t.(*types.Named); ok {
+		return named.Obj().Name()
+	}
+	return t.String()
+}
+
+// checkStubs returns an error listing the unsupported uses of the
+// packages that are replaced with stubs
+func (r *PkgRewriter) checkStubs(replacements PkgReplacement) error {
+	if uses := r.Unsupported(replacements); len(uses) > 0 {
+		return errors.Errorf("the implementation uses APIs that are not implemented by the stubs:\n%s", strings.Join(uses, "\n"))
+	}
+	return nil
+}
@@ -4,8 +4,6 @@ import (
 	"flag"
 	"os"
 	"testing"
-
-	opt "github.com/moznion/go-optional"
 )
 
 var (
@@ -24,13 +22,13 @@ func rewrite(file string, r Rewriter) (string, error) {
 }
 
 func testRewriter(t *testing.T, f string) {
-	protoReplacements := map[string]string{
-		"cofaas_orig/protos/helloworld": "cofaas/protos/helloworld",
-		"cofaas_orig/protos/prodcon":    "cofaas/protos/prodcon",
-		"google.golang.org/grpc": "github.com/truls/cofaas-go/stubs/grpc",
-		"google.golang.org/grpc/reflection": "github.com/truls/cofaas-go/stubs/grpc/reflection",
-		"google.golang.org/grpc/credentials/insecure": "github.com/truls/cofaas-go/stubs/grpc/credentials/insecure",
-		"net": "github.com/truls/cofaas-go/stubs/net",
+	protoReplacements := PkgReplacement{
+		"cofaas_orig/protos/helloworld":               {Name: "cofaas/protos/helloworld"},
+		"cofaas_orig/protos/prodcon":                  {Name: "cofaas/protos/prodcon"},
+		"google.golang.org/grpc":                      {Name: "github.com/truls/cofaas-go/stubs/grpc"},
+		"google.golang.org/grpc/reflection":           {Name: "github.com/truls/cofaas-go/stubs/grpc/reflection"},
+		"google.golang.org/grpc/credentials/insecure": {Name: "github.com/truls/cofaas-go/stubs/grpc/credentials/insecure"},
+		"net": {Name: "github.com/truls/cofaas-go/stubs/net"},
 	}
 
 	r, err := GetRewriter(f, protoReplacements)
@@ -38,7 +36,7 @@ func testRewriter(t *testing.T, f string) {
 		t.Error(err)
 	}
 
-	compareGoldenFile(t, f, nil, func(file string, a2 opt.Option[string]) (string, error) {
+	compareGoldenFile(t, f, nil, func(file string, a2 []string) (string, error) {
 		return rewrite(file, r)
 	}, *update, *verbose)
 
@@ -0,0 +1,154 @@
+package cofaas
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-errors/errors"
+	"golang.org/x/mod/modfile"
+)
+
+//go:generate go run ./protogen/stubapi stubapi_gen.go stubs/grpc stubs/net
+
+// GenStubAPI returns the source of stubapi_gen.go which lists the
+// exported API of the packages of the stub modules in moduleDirs.
+// Methods are listed as <type>.<method>.
+func GenStubAPI(moduleDirs ...string) (string, error) {
+	api := make(map[string][]string)
+	for _, modDir := range moduleDirs {
+		data, err := os.ReadFile(filepath.Join(modDir, "go.mod"))
+		if err != nil {
+			return "", errors.Wrap(err, 0)
+		}
+		modulePath := modfile.ModulePath(data)
+
+		err = filepath.WalkDir(modDir, func(p string, d os.DirEntry, err error) error {
+			if err != nil || !d.IsDir() {
+				return err
+			}
+			names, err := exportedNames(p)
+			if err != nil || len(names) == 0 {
+				return err
+			}
+			rel, err := filepath.Rel(modDir, p)
+			if err != nil {
+				return err
+			}
+			api[path.Join(modulePath, filepath.ToSlash(rel))] = names
+			return nil
+		})
+		if err != nil {
+			return "", errors.Wrap(err, 0)
+		}
+	}
+
+	var pkgs []string
+	for p := range api {
+		pkgs = append(pkgs, p)
+	}
+	sort.Strings(pkgs)
+
+	var res strings.Builder
+	res.WriteString("// Code generated by protogen/stubapi. DO NOT EDIT.\n\n")
+	res.WriteString("package cofaas\n\n")
+	res.WriteString("// stubAPI lists the exported API of the stub packages indexed by\n// their import paths\n")
+	res.WriteString("var stubAPI = map[string][]string{\n")
+	for _, p := range pkgs {
+		fmt.Fprintf(&res, "\t%q: {\n", p)
+		for _, n := range api[p] {
+			fmt.Fprintf(&res, "\t\t%q,\n", n)
+		}
+		res.WriteString("\t},\n")
+	}
+	res.WriteString("}\n")
+	return res.String(), nil
+}
+
+// exportedNames returns the sorted exported declarations of the go
+// files in dir
+func exportedNames(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	var res []string
+	fset := token.NewFileSet()
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".go") || strings.HasSuffix(e.Name(), "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(dir, e.Name()), nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+		for _, decl := range f.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				if !d.Name.IsExported() {
+					continue
+				}
+				if d.Recv == nil {
+					res = append(res, d.Name.Name)
+					continue
+				}
+				recv := d.Recv.List[0].Type
+				if star, ok := recv.(*ast.StarExpr); ok {
+					recv = star.X
+				}
+				if id, ok := recv.(*ast.Ident); ok {
+					res = append(res, id.Name+"."+d.Name.Name)
+				}
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					switch s := spec.(type) {
+					case *ast.TypeSpec:
+						if s.Name.IsExported() {
+							res = append(res, s.Name.Name)
+						}
+					case *ast.ValueSpec:
+						for _, n := range s.Names {
+							if n.IsExported() {
+								res = append(res, n.Name)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+// stubTypes maps the types of the replaced packages to the stub
+// types implementing their methods where their names differ
+var stubTypes = map[string]string{
+	"net.Listener": "ListenerImpl",
+}
+
+// stubMethod returns the name of the method of the stubs implementing
+// method of the type typeName of the package pkgPath
+func stubMethod(pkgPath string, typeName string, method string) string {
+	if t, ok := stubTypes[pkgPath+"."+typeName]; ok {
+		typeName = t
+	}
+	return typeName + "." + method
+}
+
+// stubImplements reports whether the stub package stubPath provides
+// the declaration name. Methods are named <type>.<method>.
+func stubImplements(stubPath string, name string) bool {
+	for _, n := range stubAPI[stubPath] {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
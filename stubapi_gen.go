@@ -0,0 +1,35 @@
+// Code generated by protogen/stubapi. DO NOT EDIT.
+
+package cofaas
+
+// stubAPI lists the exported API of the stub packages indexed by
+// their import paths
+var stubAPI = map[string][]string{
+	"github.com/truls/cofaas-go/stubs/grpc": {
+		"CallOption",
+		"ClientConn",
+		"ClientConn.Close",
+		"ClientConn.Invoke",
+		"ClientConn.NewStream",
+		"Dial",
+		"NewServer",
+		"Server",
+		"Server.Serve",
+		"WithBlock",
+		"WithTransportCredentials",
+	},
+	"github.com/truls/cofaas-go/stubs/grpc/credentials/insecure": {
+		"NewCredentials",
+	},
+	"github.com/truls/cofaas-go/stubs/grpc/reflection": {
+		"Register",
+	},
+	"github.com/truls/cofaas-go/stubs/net": {
+		"Addr",
+		"Listen",
+		"ListenerImpl",
+		"ListenerImpl.Accept",
+		"ListenerImpl.Addr",
+		"ListenerImpl.Close",
+	},
+}
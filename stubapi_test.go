@@ -0,0 +1,20 @@
+package cofaas
+
+import (
+	"os"
+	"testing"
+)
+
+func TestStubAPIUpToDate(t *testing.T) {
+	res, err := GenStubAPI("stubs/grpc", "stubs/net")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := os.ReadFile("stubapi_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != string(expected) {
+		t.Error("stubapi_gen.go is out of date. Run go generate")
+	}
+}
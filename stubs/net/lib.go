@@ -8,7 +8,7 @@ type ListenerImpl struct {
 type Addr struct {
 }
 
-func (*ListenerImpl) Acccept() (net.Conn, error) {
+func (*ListenerImpl) Accept() (net.Conn, error) {
 	return nil, nil
 }
 
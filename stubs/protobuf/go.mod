@@ -0,0 +1,3 @@
+module github.com/truls/cofaas-go/stubs/protobuf
+
+go 1.20
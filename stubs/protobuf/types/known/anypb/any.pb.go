@@ -0,0 +1,56 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go (unknown)
+// 	protoc        (unknown)
+// source: google/protobuf/any.proto
+
+// Package anypb contains generated types for google/protobuf/any.proto.
+//
+// The Any message is a dynamic representation of any other message value.
+// It is functionally a tuple of the full name of the remote message type and
+// the serialized bytes of the remote message value.
+//
+// Since this package does not depend on the protobuf runtime, it cannot
+// marshal or unmarshal the remote message value. The type URL and the
+// serialized value are available through the TypeUrl and Value fields and
+// the full name of the remote message type through the MessageName method:
+//
+//	if any.MessageName() == "foo.MyMessage" {
+//		... // make use of any.Value
+//	}
+package anypb
+
+import (
+	strings "strings"
+)
+
+type Any struct {
+	TypeUrl string
+	Value   []byte
+}
+
+// MessageName reports the full name of the message type stored in x
+// as derived from its type URL. An empty name is returned if the
+// type URL is invalid.
+func (x *Any) MessageName() string {
+	url := x.GetTypeUrl()
+	name := url
+	if i := strings.LastIndexByte(url, '/'); i >= 0 {
+		name = name[i+len("/"):]
+	}
+	return name
+}
+
+func (x *Any) GetTypeUrl() string {
+	if x != nil {
+		return x.TypeUrl
+	}
+	return ""
+}
+
+func (x *Any) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
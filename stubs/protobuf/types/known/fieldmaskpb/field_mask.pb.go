@@ -0,0 +1,50 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go (unknown)
+// 	protoc        (unknown)
+// source: google/protobuf/field_mask.proto
+
+// Package fieldmaskpb contains generated types for google/protobuf/field_mask.proto.
+//
+// The FieldMask message represents a set of symbolic field paths.
+// The paths are specific to some target message type,
+// which is not stored within the FieldMask message itself.
+//
+// # Constructing a FieldMask
+//
+// The New function is used construct a FieldMask:
+//
+//	fm := fieldmaskpb.New("field.name", "field.number")
+//	... // make use of fm
+//
+// Once a FieldMask message has been constructed,
+// the Append method can be used to insert additional paths to the path set:
+//
+//	fm.Append("options")
+//
+// Since this package does not depend on the protobuf runtime, the paths
+// are not validated against the target message type.
+package fieldmaskpb
+
+type FieldMask struct {
+	Paths []string
+}
+
+// New constructs a field mask from a list of paths. Unlike the
+// upstream implementation, the paths are not validated against a
+// message type.
+func New(paths ...string) *FieldMask {
+	return &FieldMask{Paths: append([]string(nil), paths...)}
+}
+
+// Append appends a list of paths to the mask.
+func (x *FieldMask) Append(paths ...string) {
+	x.Paths = append(x.Paths, paths...)
+}
+
+func (x *FieldMask) GetPaths() []string {
+	if x != nil {
+		return x.Paths
+	}
+	return nil
+}
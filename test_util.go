@@ -6,13 +6,24 @@ import (
 	"testing"
 
 	"github.com/go-errors/errors"
-	opt "github.com/moznion/go-optional"
 	"github.com/sergi/go-diff/diffmatchpatch"
 )
 
-func call1test(f func(string) (string, error)) func(string, opt.Option[string]) (string, error) {
-	return func(a1 string, a2 opt.Option[string]) (string, error) {
-		return f(a1)
+func call1test(f func(string, []string) (string, error), includes ...string) func(string, []string) (string, error) {
+	return func(a1 string, a2 []string) (string, error) {
+		return f(a1, getTestInputs(includes))
+	}
+}
+
+func call2test(f func(string, []string, []string, ...string) (string, error), a3 ...string) func(string, []string) (string, error) {
+	return call2testIncludes(f, nil, a3...)
+}
+
+// call2testIncludes is like call2test but resolves imported protocols
+// in the test input directories includes
+func call2testIncludes(f func(string, []string, []string, ...string) (string, error), includes []string, a3 ...string) func(string, []string) (string, error) {
+	return func(a1 string, a2 []string) (string, error) {
+		return f(a1, a2, getTestInputs(includes), a3...)
 	}
 }
 
@@ -20,6 +31,14 @@ func getTestInput(file string) string {
 	return path.Join("testdata", file)
 }
 
+func getTestInputs(files []string) []string {
+	var res []string
+	for _, f := range files {
+		res = append(res, getTestInput(f))
+	}
+	return res
+}
+
 func getGoldenFileName(file string) string {
 	return getTestInput(file) + ".golden"
 }
@@ -43,11 +62,21 @@ func getComparison(a string, b string) string {
 	return dmp.DiffPrettyText(diffs)
 }
 
-func compareGoldenFile(t *testing.T, goldenFile1 string, extraInput opt.Option[string], transformer func(string, opt.Option[string]) (string, error), doUpdate bool, verbose bool) {
+func compareGoldenFile(t *testing.T, goldenFile1 string, extraInputs []string, transformer func(string, []string) (string, error), doUpdate bool, verbose bool) {
+	compareGoldenFileAs(t, goldenFile1, goldenFile1, extraInputs, transformer, doUpdate, verbose)
+}
 
-	fn := getTestInput(goldenFile1)
-	fn2 := opt.Map(extraInput, getTestInput)
-	expected, err := readGoldenFile(goldenFile1)
+// compareGoldenFileAs is like compareGoldenFile but compares the output
+// for the input file to the golden file of goldenName. Generators
+// sharing an input name their golden files <input>.<generator>.
+func compareGoldenFileAs(t *testing.T, input string, goldenName string, extraInputs []string, transformer func(string, []string) (string, error), doUpdate bool, verbose bool) {
+
+	fn := getTestInput(input)
+	var fn2 []string
+	for _, f := range extraInputs {
+		fn2 = append(fn2, getTestInput(f))
+	}
+	expected, err := readGoldenFile(goldenName)
 	if err != nil {
 		t.Error(err)
 	}
@@ -60,7 +89,7 @@ func compareGoldenFile(t *testing.T, goldenFile1 string, extraInput opt.Option[s
 	}
 
 	if doUpdate {
-		if err := writeGoldenFile(goldenFile1, actual); err != nil {
+		if err := writeGoldenFile(goldenName, actual); err != nil {
 			t.Error(err)
 		}
 	} else {
@@ -0,0 +1,5 @@
+package reply
+
+func Wrap(msg string) string {
+	return "<" + msg + ">"
+}
@@ -0,0 +1,12 @@
+package main
+
+import (
+	"fmt"
+
+	"example.com/mono/fns/greeter/internal/reply"
+	"example.com/mono/lib/greet"
+)
+
+func main() {
+	fmt.Println(reply.Wrap(greet.Hello("world")))
+}
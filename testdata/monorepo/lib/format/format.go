@@ -0,0 +1,5 @@
+package format
+
+func Greeting(name string) string {
+	return "Hello " + name
+}
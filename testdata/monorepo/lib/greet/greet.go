@@ -0,0 +1,7 @@
+package greet
+
+import "example.com/mono/lib/format"
+
+func Hello(name string) string {
+	return format.Greeting(name)
+}
@@ -0,0 +1,11 @@
+package format
+
+import "fmt"
+
+func Greeting(name string) string {
+	return "Hello " + name
+}
+
+func main() {
+	fmt.Println(Greeting("main"))
+}
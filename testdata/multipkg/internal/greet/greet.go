@@ -0,0 +1,16 @@
+package greet
+
+import (
+	"net"
+
+	"example.com/greeter/internal/format"
+)
+
+func Hello(addr string) string {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return format.Greeting(addr)
+	}
+	lis.Close()
+	return format.Greeting("listener")
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"fmt"
+	"net"
+
+	"example.com/greeter/internal/greet"
+)
+
+func main() {
+	lis, err := net.Listen("tcp", ":50051")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(lis.Addr(), greet.Hello(":50052"))
+}
@@ -0,0 +1,14 @@
+module example.com/fn
+
+go 1.20
+
+replace (
+	example.com/greeter/protos/helloworld => ../protos/helloworld
+	example.com/lib => ../lib
+	golang.org/x/net => golang.org/x/net v0.17.0
+)
+
+require (
+	example.com/greeter/protos/helloworld v0.0.0-00010101000000-000000000000
+	example.com/lib v0.0.0-00010101000000-000000000000
+)
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	. "net"
+	stdnet "net"
+
+	"example.com/typed/protos/hello"
+)
+
+var entry = main
+
+func main() {
+	lis, err := Listen("tcp", ":0")
+	if err != nil {
+		panic(err)
+	}
+	defer lis.Close()
+	other, _ := stdnet.Listen("tcp", ":0")
+	fmt.Println(other.Addr(), hellopb.Request{Name: "main"})
+}
+
+func run() {
+	entry()
+}
@@ -0,0 +1,5 @@
+package hellopb
+
+type Request struct {
+	Name string
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"fmt"
+	. "net"
+	n "net"
+)
+
+func main() {
+	conn, err := Dial("tcp", "localhost:80")
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+	addr, _ := n.ResolveTCPAddr("tcp", "localhost:80")
+	lis, _ := n.Listen("tcp", ":0")
+	fmt.Println(addr.Port, lis.Addr().Network())
+	lis.Accept()
+}
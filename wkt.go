@@ -0,0 +1,111 @@
+package cofaas
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-errors/errors"
+)
+
+//go:generate go run ./protogen/wkt stubs/protobuf/types/known
+
+// WellKnownTypesModule is the module bundling the cofaas versions of
+// the protobuf well-known types
+const WellKnownTypesModule = "github.com/truls/cofaas-go/stubs/protobuf"
+
+// upstreamKnownTypesBase is the import path prefix of the well-known
+// type packages provided by the protobuf module
+const upstreamKnownTypesBase = "google.golang.org/protobuf/types/known/"
+
+// wellKnownTypes maps the files defining the protobuf well-known types
+// to the names of the Go packages generated for them
+var wellKnownTypes = map[string]string{
+	"google/protobuf/any.proto":        "anypb",
+	"google/protobuf/duration.proto":   "durationpb",
+	"google/protobuf/empty.proto":      "emptypb",
+	"google/protobuf/field_mask.proto": "fieldmaskpb",
+	"google/protobuf/struct.proto":     "structpb",
+	"google/protobuf/timestamp.proto":  "timestamppb",
+	"google/protobuf/wrappers.proto":   "wrapperspb",
+}
+
+func wellKnownTypePath(pkg string) string {
+	return WellKnownTypesModule + "/types/known/" + pkg
+}
+
+// sortedWellKnownTypes returns the well-known type files in a stable
+// order
+func sortedWellKnownTypes() []string {
+	res := make([]string, 0, len(wellKnownTypes))
+	for f := range wellKnownTypes {
+		res = append(res, f)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// WellKnownTypeReplacements maps the import paths of the upstream
+// well-known type packages to the import paths of the bundled cofaas
+// versions
+func WellKnownTypeReplacements() map[string]string {
+	res := make(map[string]string, len(wellKnownTypes))
+	for _, pkg := range wellKnownTypes {
+		res[upstreamKnownTypesBase+pkg] = wellKnownTypePath(pkg)
+	}
+	return res
+}
+
+// wellKnownTypeOpts returns the plugin options that make generated
+// code refer to the bundled cofaas versions of the well-known types
+func wellKnownTypeOpts() string {
+	opts := []string{}
+	for _, f := range sortedWellKnownTypes() {
+		opts = append(opts, "M"+f+"="+wellKnownTypePath(wellKnownTypes[f]))
+	}
+	return strings.Join(opts, ",")
+}
+
+// GenWellKnownTypes generates the cofaas versions of the well-known
+// type packages in dir
+func GenWellKnownTypes(dir string) error {
+	for _, f := range sortedWellKnownTypes() {
+		res, err := genWellKnownTypeCode(f)
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+		pkgDir := filepath.Join(dir, wellKnownTypes[f])
+		if err := os.MkdirAll(pkgDir, 0755); err != nil {
+			return errors.Wrap(err, 0)
+		}
+		outFile := strings.TrimSuffix(filepath.Base(f), ".proto") + ".pb.go"
+		if err := os.WriteFile(filepath.Join(pkgDir, outFile), []byte(res), 0644); err != nil {
+			return errors.Wrap(err, 0)
+		}
+	}
+	return nil
+}
+
+// genWellKnownTypeCode generates Go types for the well-known type file
+// which is resolved from the include path of protoc
+func genWellKnownTypeCode(file string) (string, error) {
+	dir, err := os.MkdirTemp("", "cofaas-protogen")
+	if err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+	g := &generator{
+		dir:      dir,
+		fname:    file,
+		pkg_name: strings.TrimSuffix(file, ".proto"),
+		frontend: frontend,
+	}
+	defer g.cleanup()
+
+	files, err := g.runPlugin(typesPlugin())
+	if err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+
+	return g.output(files, g.getOutputFile(".pb.go"))
+}